@@ -47,12 +47,28 @@ func main() {
 	//   unless the user chooses Save & Run in the UI.
 	// - -run: execute the background backup/delete process.
 	// - -setup: explicit alias for setup/configuration mode.
+	// - -plan <file>: scan like -run, but only write a reviewable deletion plan
+	//   (<file>.json and <file>.csv). Nothing is copied or deleted.
+	// - -apply-plan <file>: execute a reviewed plan instead of rescanning. This is
+	//   destructive, so it requires -run like any other maintenance run.
+	// - -restore <DDMmmYY>[..<DDMmmYY>]: copy files back out of dated backup
+	//   folders. Runs on its own (never with -run/-setup/-plan/-apply-plan) and never
+	//   deletes; -restore-match/-target/-conflict/-dry-run refine it.
 	//
 	// Runtime flags only override config.ini when they are explicitly passed.
 	// -----------------------------------------------------------------------------
 	var (
 		runMode   = flag.Bool("run", false, "Run the background backup/delete maintenance process")
 		setupMode = flag.Bool("setup", false, "Open the setup/configuration UI and exit unless Save & Run is selected")
+		planFile  = flag.String("plan", "", "Write a deletion plan (<file>.json and <file>.csv) instead of copying or deleting files")
+		applyPlan = flag.String("apply-plan", "", "With -run, process exactly the files listed in a plan generated by -plan instead of rescanning")
+
+		// Restore mode.
+		restoreDates    = flag.String("restore", "", "Restore files from a backup date folder (30Jan26) or range (01Jan26..31Jan26)")
+		restoreMatch    = flag.String("restore-match", "", "Only restore files whose original path matches this glob")
+		restoreTarget   = flag.String("restore-target", "", "Restore under this directory instead of original locations")
+		restoreConflict = flag.String("restore-conflict", string(types.RestoreSkip), "When the destination exists: skip, overwrite, or rename")
+		restoreDryRun   = flag.Bool("restore-dry-run", false, "Log what -restore would do without writing anything")
 
 		// Retention policy for candidate files (only files older than this are processed).
 		days = flag.Int("days", defaultRuntime.Days, "Number of days to retain files")
@@ -93,6 +109,23 @@ func main() {
 		return
 	}
 
+	if *planFile != "" && *applyPlan != "" {
+		fmt.Fprintln(os.Stderr, "-plan and -apply-plan cannot be used together")
+		os.Exit(2)
+	}
+
+	restoreMode := *restoreDates != ""
+	if restoreMode && (*runMode || *setupMode || *planFile != "" || *applyPlan != "") {
+		fmt.Fprintln(os.Stderr, "-restore cannot be used with -run, -setup, -plan, or -apply-plan")
+		os.Exit(2)
+	}
+	switch types.RestoreConflict(*restoreConflict) {
+	case types.RestoreSkip, types.RestoreOverwrite, types.RestoreRename:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -restore-conflict %q (use skip, overwrite, or rename)\n", *restoreConflict)
+		os.Exit(2)
+	}
+
 	cliRuntime := runtimeOverridesFromFlags(seenFlags, *days, *logRetention, *walkers, *queueSize, *maxFiles, *maxRuntime, *cooldown, *retries, *noBackup)
 
 	// -----------------------------------------------------------------------------
@@ -102,8 +135,17 @@ func main() {
 	// defaults -> config.ini -> explicit CLI overrides.
 	// -----------------------------------------------------------------------------
 	cfg := types.ApplyRuntimeConfig(types.AppConfig{
-		ConfigDir: *configDir,
-		BackupDir: "",
+		ConfigDir:     *configDir,
+		BackupDir:     "",
+		PlanFile:      *planFile,
+		ApplyPlanFile: *applyPlan,
+		Restore: types.RestoreOptions{
+			Dates:    *restoreDates,
+			Match:    *restoreMatch,
+			Target:   *restoreTarget,
+			Conflict: types.RestoreConflict(*restoreConflict),
+			DryRun:   *restoreDryRun,
+		},
 		LogSettings: logging.LogSettings{
 			NoLogs: *noLogs,
 			LogDir: *logDir,
@@ -126,9 +168,10 @@ func main() {
 	//
 	// A plain double-click or plain CLI launch opens setup/configuration. This keeps
 	// destructive backup/delete work behind an explicit -run flag, unless the user
-	// chooses Save & Run from the Windows setup UI.
+	// chooses Save & Run from the Windows setup UI. -plan is non-destructive and
+	// runs without the UI, like -run. -restore is an explicit mode of its own.
 	// -----------------------------------------------------------------------------
-	if !*runMode || *setupMode {
+	if (!*runMode && *planFile == "" && !restoreMode) || *setupMode {
 		action, err := pf.RunSetup(cfg.ConfigDir, root)
 		if err != nil {
 			log.Errorf("setup failed: %v", err)
@@ -168,6 +211,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	// -----------------------------------------------------------------------------
+	// Restore mode replaces the maintenance run entirely.
+	// -----------------------------------------------------------------------------
+	if restoreMode {
+		if err := app.Restore(cfg, log); err != nil {
+			log.Errorf("restore exited with error: %v", err)
+			fmt.Fprintf(os.Stderr, "restore exited with error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// -----------------------------------------------------------------------------
 	// Run the application.
 	// -----------------------------------------------------------------------------
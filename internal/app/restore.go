@@ -0,0 +1,83 @@
+package app
+
+import (
+	"fmt"
+
+	"file-maintenance/internal/config"
+	"file-maintenance/internal/logging"
+	"file-maintenance/internal/maintenance"
+	"file-maintenance/internal/types"
+)
+
+// Restore copies files back out of dated backup folders (-restore mode).
+//
+// config.ini is still required: it provides the backup root, and its [paths]
+// entries are used to find original locations for backups that have no
+// manifest.jsonl record. Runtime settings do not apply; restore never deletes.
+//
+// With several [backup] destinations, each is searched in config order. In
+// failover mode a day's files can be spread over several destinations, so all
+// of them are restored from; in mirror mode every destination holds the same
+// files, so the first one with matching folders is used. Per-path dest= roots
+// are always searched as well. Unreachable destinations are skipped with a
+// warning.
+func Restore(cfg types.AppConfig, log *logging.Logger) error {
+	plan, _, err := config.ReadAllConfig(cfg.ConfigDir, log)
+	if err != nil {
+		return err
+	}
+
+	opts := cfg.Restore
+	opts.Key = plan.Backup.Key
+	target := "original locations"
+	if opts.Target != "" {
+		target = opts.Target
+	}
+	if opts.Match != "" {
+		log.Infof("Restore filter: %s", opts.Match)
+	}
+
+	roots := allBackupRoots(plan)
+	mirrorFound := false
+
+	var (
+		total maintenance.RestoreSummary
+		found bool
+	)
+	for i, backupDir := range roots {
+		isDefault := i < len(plan.BackupDirs)
+		if isDefault && mirrorFound {
+			// Mirrors hold the same files; one of them is enough.
+			continue
+		}
+
+		log.Infof("Restore mode: backups=%s dates=%s target=%s conflict=%s", backupDir, opts.Dates, target, opts.Conflict)
+
+		summary, err := maintenance.Restore(plan.Paths, backupDir, opts, log)
+		if err != nil {
+			if len(roots) == 1 {
+				return err
+			}
+			log.Warnf("Skipping backup location %s: %v", backupDir, err)
+			continue
+		}
+		found = true
+		total.Restored += summary.Restored
+		total.Skipped += summary.Skipped
+		total.Failed += summary.Failed
+
+		if isDefault && plan.Backup.Mode == types.BackupModeMirror {
+			mirrorFound = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no backup location has folders matching %s", opts.Dates)
+	}
+
+	// Partial restores must be visible to callers and scripts.
+	if total.Failed > 0 {
+		return fmt.Errorf("restore finished with %d failed file(s)", total.Failed)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 
 	"file-maintenance/internal/config"
 	"file-maintenance/internal/logging"
@@ -12,6 +13,7 @@ import (
 type runtimePlatform interface {
 	ShowCritical(title, message string)
 	AvailableBytes(path string) (uint64, error)
+	TotalBytes(path string) (uint64, error)
 }
 
 func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cliRuntime types.RuntimeConfigOverrides) error {
@@ -35,6 +37,13 @@ func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cli
 	runtimeCfg = types.ApplyRuntimeOverrides(runtimeCfg, cliRuntime)
 	cfg = types.ApplyRuntimeConfig(cfg, runtimeCfg)
 	cfg.BackupDir = plan.BackupDir
+	cfg.BackupDirs = plan.BackupDirs
+	cfg.Backup = plan.Backup
+	cfg.Throttle = plan.Throttle
+
+	if cfg.PlanFile != "" {
+		log.Infof("Plan mode enabled - no files will be copied or deleted. Plan output: %s", cfg.PlanFile)
+	}
 
 	pathconfig := plan.Paths
 	if cfg.NoBackup {
@@ -44,6 +53,22 @@ func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cli
 		}
 	}
 
+	// -----------------------------------------------------------------------------
+	// Load a reviewed plan when -apply-plan is used.
+	//
+	// The plan decides which files are processed and whether each one is backed
+	// up, so backup validation below must look at the plan rather than [paths].
+	// -----------------------------------------------------------------------------
+	var reviewedPlan *maintenance.PlanManifest
+	if cfg.ApplyPlanFile != "" {
+		manifest, err := maintenance.ReadPlanManifest(cfg.ApplyPlanFile)
+		if err != nil {
+			return err
+		}
+		reviewedPlan = &manifest
+		log.Infof("Applying plan %s (%d file(s), created %s)", cfg.ApplyPlanFile, len(manifest.Files), manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+
 	// Log paths and their backup settings.
 	for _, pc := range pathconfig {
 		backupStr := "yes"
@@ -64,15 +89,34 @@ func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cli
 	// - Skip backup validation.
 	// - Worker will run in "delete only" mode.
 	// -----------------------------------------------------------------------------
-	anyBackupEnabled := false
-	for _, pc := range pathconfig {
-		if pc.Backup {
-			anyBackupEnabled = true
-			break
+	usesDefaultBackup, pathDests := backupRootsInUse(pathconfig, reviewedPlan, cfg.NoBackup)
+
+	if usesDefaultBackup && len(plan.BackupDirs) > 1 {
+		// Several destinations: the run may start as long as enough of them are
+		// reachable for the mode (one for failover, the quorum for mirror).
+		// The worker re-checks them per batch and skips unreachable ones.
+		required := plan.Backup.RequiredCopies(len(plan.BackupDirs))
+		var unreachable []string
+		for _, dir := range plan.BackupDirs {
+			log.Infof("Backup location: %s", dir)
+			if !maintenance.CheckBackupPath(dir) {
+				log.Warnf("Backup location is not accessible: %s", dir)
+				unreachable = append(unreachable, dir)
+			}
 		}
-	}
+		if reachable := len(plan.BackupDirs) - len(unreachable); reachable < required {
+			errMsg := fmt.Sprintf(
+				"Only %d of %d backup locations are accessible, %d required:\n\n%s\n\nPlease check paths and permissions.",
+				reachable,
+				len(plan.BackupDirs),
+				required,
+				strings.Join(unreachable, "\n"),
+			)
+			platform.ShowCritical("Backup Location Error", errMsg)
 
-	if anyBackupEnabled {
+			return fmt.Errorf("backup paths not accessible: %d of %d reachable, %d required", reachable, len(plan.BackupDirs), required)
+		}
+	} else if usesDefaultBackup {
 		log.Infof("Backup location: %s", plan.BackupDir)
 
 		// Safety check:
@@ -86,8 +130,24 @@ func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cli
 
 			return fmt.Errorf("backup path not accessible: %s", plan.BackupDir)
 		}
-	} else {
+	}
+
+	// Per-path dest= roots are checked the same way, each on its own: a path
+	// with its own destination never falls back to another one.
+	for _, dest := range pathDests {
+		log.Infof("Backup location (per-path dest): %s", dest)
+		if !maintenance.CheckBackupPath(dest) {
+			errMsg := fmt.Sprintf("Backup path is not accessible: %s\n\nPlease check path and permissions.", dest)
+			platform.ShowCritical("Backup Location Error", errMsg)
+
+			return fmt.Errorf("backup path not accessible: %s", dest)
+		}
+	}
+
+	if !usesDefaultBackup && len(pathDests) == 0 {
 		log.Warn("All paths have backup disabled - running in delete-only mode")
+	} else if cfg.Backup.Key != nil {
+		log.Infof("Backups are encrypted with key %s", cfg.Backup.Key.ID)
 	}
 
 	// -----------------------------------------------------------------------------
@@ -102,18 +162,50 @@ func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cli
 	// Important:
 	// - We must return Worker errors; otherwise failures are invisible to callers
 	//   and Task Scheduler exit codes.
+	// - With -apply-plan, ApplyPlan replaces scanning with the reviewed file list.
 	// -----------------------------------------------------------------------------
-	if err := maintenance.Worker(pathconfig, plan.BackupDir, cfg, log, platform); err != nil {
+	if reviewedPlan != nil {
+		if err := maintenance.ApplyPlan(pathconfig, *reviewedPlan, plan.BackupDir, cfg, log, platform); err != nil {
+			return err
+		}
+	} else if err := maintenance.Worker(pathconfig, plan.BackupDir, cfg, log, platform); err != nil {
 		return err
 	}
 
+	// -----------------------------------------------------------------------------
+	// Housekeeping: prune expired backup folders.
+	//
+	// [backup] retention=N removes <backupRoot>/<DDMmmYY> folders dated more than
+	// N days ago. Ages come from the folder names, and folders whose names are not
+	// dates are never touched (see maintenance.RemoveOldBackups).
+	//
+	// Plan mode never deletes anything, so pruning is skipped there too. Each
+	// destination is checked here, because with several destinations some may
+	// have been unreachable all run; those are skipped with a warning rather
+	// than failing an otherwise good run.
+	// -----------------------------------------------------------------------------
+	if cfg.Backup.Retention > 0 && cfg.PlanFile == "" {
+		for _, dir := range allBackupRoots(plan) {
+			if !maintenance.CheckBackupPath(dir) {
+				log.Warnf("Backup path is not accessible, skipping backup retention: %s", dir)
+				continue
+			}
+			removed, err := maintenance.RemoveOldBackups(dir, cfg.Backup.Retention, log)
+			if err != nil {
+				return err
+			}
+			log.Countf("Amount of expired backup folders removed from %s (retention %d days): %d", dir, cfg.Backup.Retention, removed)
+		}
+	}
+
 	// -----------------------------------------------------------------------------
 	// Housekeeping: prune old logs.
 	//
 	// Only do this when file logging is enabled. When -no-logs is set, LogDir may
-	// be unused and we should not attempt filesystem cleanup.
+	// be unused and we should not attempt filesystem cleanup. Plan mode never
+	// deletes anything, including old logs.
 	// -----------------------------------------------------------------------------
-	if !cfg.LogSettings.NoLogs {
+	if !cfg.LogSettings.NoLogs && cfg.PlanFile == "" {
 		if err := maintenance.RemoveOldLogs(cfg.LogSettings.LogDir, cfg.LogRetention); err != nil {
 			return err
 		}
@@ -121,3 +213,66 @@ func Run(cfg types.AppConfig, log *logging.Logger, platform runtimePlatform, cli
 
 	return nil
 }
+
+// backupRootsInUse reports which backup roots this run writes to: whether any
+// backed-up file goes to the [backup] destinations, and the distinct per-path
+// dest= roots in config order.
+//
+// With a reviewed plan only its entries that are backed up (by the plan or by
+// their configured path) count, each resolved through its configured path
+// (the plan does not choose destinations).
+func backupRootsInUse(pathconfig []types.PathConfig, reviewedPlan *maintenance.PlanManifest, noBackup bool) (usesDefault bool, dests []string) {
+	seen := make(map[string]bool)
+	use := func(pc types.PathConfig) {
+		if pc.Dest == "" {
+			usesDefault = true
+			return
+		}
+		if !seen[pc.Dest] {
+			seen[pc.Dest] = true
+			dests = append(dests, pc.Dest)
+		}
+	}
+
+	if reviewedPlan == nil {
+		for _, pc := range pathconfig {
+			if pc.Backup {
+				use(pc)
+			}
+		}
+		return usesDefault, dests
+	}
+
+	configured := make(map[string]types.PathConfig, len(pathconfig))
+	for _, pc := range pathconfig {
+		configured[pc.Path] = pc
+	}
+	for _, entry := range reviewedPlan.Files {
+		if noBackup {
+			continue
+		}
+		// Matches runWorker: the plan or the current entry may ask for backup.
+		if pc, ok := configured[entry.ConfigPath]; ok && (entry.Backup || pc.Backup) {
+			use(pc)
+		}
+	}
+	return usesDefault, dests
+}
+
+// allBackupRoots returns every backup root that may hold dated folders: the
+// [backup] destinations followed by distinct per-path dest= roots. Retention and
+// restore both cover all of them.
+func allBackupRoots(plan types.FilePlanConfig) []string {
+	roots := append([]string{}, plan.BackupDirs...)
+	seen := make(map[string]bool, len(roots))
+	for _, root := range roots {
+		seen[root] = true
+	}
+	for _, pc := range plan.Paths {
+		if pc.Dest != "" && !seen[pc.Dest] {
+			seen[pc.Dest] = true
+			roots = append(roots, pc.Dest)
+		}
+	}
+	return roots
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"file-maintenance/internal/types"
+)
+
+// backupKeySize is the AES-256 key length in bytes.
+const backupKeySize = 32
+
+// loadBackupKey reads the [backup] encrypt-key file.
+//
+// The file holds the 32-byte key as 64 hex characters (surrounding whitespace
+// is ignored) or raw, e.g. created with
+// "openssl rand -hex 32 > backup.key". A relative path is resolved against
+// configDir.
+//
+// On Linux and macOS the file must not be readable or writable by group or
+// others: a key anyone can read protects nothing, so a loose mode is an error
+// rather than a warning. On Windows access is controlled by ACLs, which are
+// not checked here; restrict the file to the account that runs the task.
+func loadBackupKey(configDir, keyFile string) (*types.BackupKey, error) {
+	if !filepath.IsAbs(keyFile) {
+		keyFile = filepath.Join(configDir, keyFile)
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read encrypt-key: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("encrypt-key %s is not a regular file", keyFile)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return nil, fmt.Errorf("encrypt-key %s is accessible by other users (mode %04o); restrict it with chmod 600", keyFile, info.Mode().Perm())
+	}
+
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read encrypt-key: %w", err)
+	}
+
+	// Hex wins when the content parses as hex, so a too-short hex key (e.g. 32
+	// hex characters) is rejected instead of being taken as 32 raw bytes.
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		key = b
+	}
+	if len(key) != backupKeySize {
+		return nil, fmt.Errorf("encrypt-key %s must hold a %d-byte key, raw or as %d hex characters", keyFile, backupKeySize, 2*backupKeySize)
+	}
+
+	sum := sha256.Sum256(key)
+	return &types.BackupKey{ID: hex.EncodeToString(sum[:8]), Key: key}, nil
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -22,6 +24,7 @@ import (
 //	[paths]
 //	C:\temp\old, yes
 //	\\server\share\incoming, no
+//	C:\Temp\scans, backup=yes, days=30, retries=5
 //
 // Optional sections:
 //
@@ -37,13 +40,32 @@ import (
 //	max-files=0
 //	max-runtime=55m
 //	no-backup=false
+//	max-bandwidth=20MB/s
+//	max-ops-per-second=200
+//	full-speed=22:00-06:00
+//
+// [backup] may also set hash= (sha256, sha512, sha1, md5), verify= (yes/no),
+// collision= (version, keep) and retention= (days) to control how backup copies
+// are written and kept, and min-free= / min-free-percent= to keep a reserve free
+// on the backup volume; see parseBackupOptions. path= may list several
+// destinations separated by ';', used according to mode= (failover, mirror) and
+// quorum=. compress=gzip (with compress-level= and compress-ratio=) stores
+// backups compressed, encrypt-key= encrypts them with the key in that file
+// (see loadBackupKey), layout=objects deduplicates them by content, and
+// layout=archive bundles them into one archive= (zip, tar, tar.gz) per path.
+//
+// [advanced] max-bandwidth=, max-ops-per-second= and full-speed= throttle
+// backup I/O; see parseThrottleOptions.
 //
 // Path entries support both folders and individual files. Each path can opt in
-// or out of backup with yes/no. If omitted, backup defaults to enabled.
+// or out of backup with yes/no. If omitted, backup defaults to enabled. Entries
+// may also override days, retries, cooldown, and max-files for that path only.
 //
 // Errors:
 //   - Returns an error if config.ini cannot be read.
 //   - Returns an error if [backup] section is missing or has no path.
+//   - Returns an error if any [backup] option other than path is invalid.
+//   - Returns an error if a throttle option in [advanced] is invalid.
 //   - Returns an error if [paths] section is missing or contains no valid paths.
 //   - No validation of path existence is performed here; that is deferred
 //     to later stages so configuration errors fail fast and explicitly.
@@ -76,6 +98,25 @@ func ReadAllConfig(configDir string, log *logging.Logger) (types.FilePlanConfig,
 		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, fmt.Errorf("missing 'path' key in [backup] section")
 	}
 
+	backupDirs, err := parseBackupDirs(backupPath)
+	if err != nil {
+		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, err
+	}
+
+	backupOptions, err := parseBackupOptions(backupSection)
+	if err != nil {
+		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, err
+	}
+	if backupOptions.EncryptKeyFile != "" {
+		backupOptions.Key, err = loadBackupKey(configDir, backupOptions.EncryptKeyFile)
+		if err != nil {
+			return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, err
+		}
+	}
+	if backupOptions.Quorum > len(backupDirs) {
+		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, fmt.Errorf("quorum=%d in [backup] section exceeds the %d configured destination(s)", backupOptions.Quorum, len(backupDirs))
+	}
+
 	pathsSection, ok := sections["paths"]
 	if !ok {
 		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, fmt.Errorf("missing [paths] section in config.ini")
@@ -85,13 +126,28 @@ func ReadAllConfig(configDir string, log *logging.Logger) (types.FilePlanConfig,
 	if err != nil {
 		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, err
 	}
+	if backupOptions.Layout == types.LayoutArchive {
+		for _, pc := range pathconfig {
+			if pc.Compress == types.CompressGzip {
+				return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, fmt.Errorf("compress=gzip on %s does not apply to layout=archive", pc.Path)
+			}
+		}
+	}
 	if len(pathconfig) == 0 {
 		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, fmt.Errorf("missing valid path entries in [paths] section")
 	}
 
+	throttle, err := parseThrottleOptions(sections["advanced"])
+	if err != nil {
+		return types.FilePlanConfig{}, types.RuntimeConfigOverrides{}, err
+	}
+
 	plan := types.FilePlanConfig{
-		BackupDir: backupPath,
-		Paths:     pathconfig,
+		BackupDir:  backupDirs[0],
+		BackupDirs: backupDirs,
+		Backup:     backupOptions,
+		Throttle:   throttle,
+		Paths:      pathconfig,
 	}
 
 	runtimeOverrides := parseRuntimeSettings(sections)
@@ -136,8 +192,12 @@ func parseIniSections(content string) (map[string]map[string]string, map[string]
 			return nil, nil, fmt.Errorf("line outside of section: %s", line)
 		}
 
-		// Check if line contains '='
-		if strings.Contains(line, "=") {
+		// Check if line contains '='.
+		//
+		// [paths] entries may carry key=value options after the path
+		// (e.g. "C:\scans, days=30"), so in that section only an explicit
+		// "paths=" key is treated as a key-value pair.
+		if strings.Contains(line, "=") && !(currentSection == "paths" && !isPathsKeyLine(line)) {
 			parts := strings.SplitN(line, "=", 2)
 			if len(parts) == 2 {
 				key := strings.TrimSpace(parts[0])
@@ -179,60 +239,204 @@ func parsePathsSection(log *logging.Logger, section map[string]string, standalon
 			continue
 		}
 
-		// Parse path and optional backup setting
-		path, backup, err := parsePathLine(line)
+		// Parse path, backup setting, and per-path overrides
+		pc, err := parsePathLine(line)
 		if err != nil {
 			log.Warnf("Skipping malformed line in config.ini [paths]: %s (error: %v)", line, err)
 			continue
 		}
 
 		// Check if path is a directory or file
-		isDir := true
-		fi, err := os.Stat(path)
+		pc.IsDir = true
+		fi, err := os.Stat(pc.Path)
 		if err == nil {
-			isDir = fi.IsDir()
+			pc.IsDir = fi.IsDir()
 		}
 
-		config = append(config, types.PathConfig{
-			Path:   path,
-			Backup: backup,
-			IsDir:  isDir,
-		})
+		config = append(config, pc)
 	}
 
 	return config, nil
 }
 
+// isPathsKeyLine reports whether a [paths] line uses the "paths=" key form
+// rather than being a path entry with key=value options.
+func isPathsKeyLine(line string) bool {
+	key, _, _ := strings.Cut(line, "=")
+	return strings.EqualFold(strings.TrimSpace(key), "paths")
+}
+
 // parsePathLine parses a single path entry from paths section.
 //
-// Returns:
-//   - path: the file or folder path
-//   - backup: true if backup is enabled, false otherwise
-//   - error: if the line is malformed
-func parsePathLine(line string) (string, bool, error) {
-	// Check for comma-separated format
-	if strings.Contains(line, ",") {
-		parts := strings.SplitN(line, ",", 2)
-		path := strings.TrimSpace(parts[0])
-		backupStr := strings.ToLower(strings.TrimSpace(parts[1]))
-
-		if path == "" {
-			return "", false, fmt.Errorf("empty path in line: %s", line)
-		}
-
-		switch backupStr {
-		case "yes", "y", "true", "1":
-			return path, true, nil
-		case "no", "n", "false", "0":
-			return path, false, nil
+// Format:
+//
+//	path[, yes|no][, key=value]...
+//
+// Supported keys: backup, days, retries, cooldown, max-files, include, exclude,
+// age, prune-empty-dirs, prune-min-age, dest. include/exclude take ';'-separated glob
+// patterns. age takes mtime|ctime|atime|birth or name:<pattern> (e.g.
+// name:IMG_{yyyyMMdd}). prune-min-age is a Go duration such as 24h. A bare yes/no
+// token is the legacy backup setting; an unrecognized bare token keeps backup
+// enabled. Unknown keys or invalid values make the whole line malformed so a
+// typo never silently changes how a path is cleaned.
+//
+// Returns the parsed PathConfig (IsDir is left for the caller to resolve), or an
+// error if the line is malformed.
+func parsePathLine(line string) (types.PathConfig, error) {
+	parts := strings.Split(line, ",")
+	pc := types.PathConfig{
+		Path:   strings.TrimSpace(parts[0]),
+		Backup: true,
+	}
+
+	if pc.Path == "" {
+		return types.PathConfig{}, fmt.Errorf("empty path in line: %s", line)
+	}
+
+	for _, part := range parts[1:] {
+		token := strings.TrimSpace(part)
+		if token == "" {
+			continue
+		}
+
+		key, value, hasValue := strings.Cut(token, "=")
+		if !hasValue {
+			// Legacy "path, yes|no" format.
+			if backup, ok := parseYesNo(token); ok {
+				pc.Backup = backup
+			}
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "backup":
+			backup, ok := parseYesNo(value)
+			if !ok {
+				return types.PathConfig{}, fmt.Errorf("invalid backup value %q", value)
+			}
+			pc.Backup = backup
+		case "days":
+			days, err := strconv.Atoi(value)
+			if err != nil || days < 0 {
+				return types.PathConfig{}, fmt.Errorf("invalid days value %q", value)
+			}
+			pc.Days = intPtr(days)
+		case "retries":
+			retries, err := strconv.Atoi(value)
+			if err != nil || retries < 0 {
+				return types.PathConfig{}, fmt.Errorf("invalid retries value %q", value)
+			}
+			pc.Retries = intPtr(retries)
+		case "cooldown":
+			cooldown, err := parseDurationValue(value)
+			if err != nil || cooldown < 0 {
+				return types.PathConfig{}, fmt.Errorf("invalid cooldown value %q", value)
+			}
+			pc.Cooldown = durationPtr(cooldown)
+		case "max-files":
+			maxFiles, err := strconv.Atoi(value)
+			if err != nil || maxFiles < 0 {
+				return types.PathConfig{}, fmt.Errorf("invalid max-files value %q", value)
+			}
+			pc.MaxFiles = intPtr(maxFiles)
+		case "include", "exclude":
+			patterns, err := parseGlobList(value)
+			if err != nil {
+				return types.PathConfig{}, fmt.Errorf("invalid %s value %q: %w", key, value, err)
+			}
+			if key == "include" {
+				pc.Include = patterns
+			} else {
+				pc.Exclude = patterns
+			}
+		case "age":
+			basis, pattern, _ := strings.Cut(value, ":")
+			switch types.AgeBasis(strings.ToLower(strings.TrimSpace(basis))) {
+			case types.AgeModified, types.AgeChanged, types.AgeAccessed, types.AgeCreated:
+				if pattern != "" {
+					return types.PathConfig{}, fmt.Errorf("age %q does not take a pattern", basis)
+				}
+				pc.Age = types.AgeBasis(strings.ToLower(strings.TrimSpace(basis)))
+			case types.AgeFileName:
+				if strings.TrimSpace(pattern) == "" {
+					return types.PathConfig{}, fmt.Errorf("age=name requires a pattern, e.g. age=name:IMG_{yyyyMMdd}")
+				}
+				pc.Age = types.AgeFileName
+				pc.AgeNamePattern = strings.TrimSpace(pattern)
+			default:
+				return types.PathConfig{}, fmt.Errorf("invalid age value %q", value)
+			}
+		case "prune-empty-dirs":
+			prune, ok := parseYesNo(value)
+			if !ok {
+				return types.PathConfig{}, fmt.Errorf("invalid prune-empty-dirs value %q", value)
+			}
+			pc.PruneEmptyDirs = prune
+		case "prune-min-age":
+			// Plain numbers are NOT accepted here (unlike cooldown, where they
+			// mean milliseconds for backward compatibility): "prune-min-age=7"
+			// silently meaning 7ms would remove directories a user just created.
+			minAge, err := time.ParseDuration(value)
+			if err != nil || minAge < 0 {
+				return types.PathConfig{}, fmt.Errorf("invalid prune-min-age value %q (use a duration such as 24h)", value)
+			}
+			pc.PruneMinAge = durationPtr(minAge)
+		case "dest":
+			// One root only: several ';'-separated destinations with a mode
+			// are a [backup] path feature.
+			if value == "" || strings.Contains(value, ";") {
+				return types.PathConfig{}, fmt.Errorf("invalid dest value %q (one backup root)", value)
+			}
+			pc.Dest = value
+		case "compress":
+			c, err := parseCompression(value)
+			if err != nil {
+				return types.PathConfig{}, err
+			}
+			pc.Compress = c
 		default:
-			// Unrecognized backup setting, default to true (backup enabled)
-			return path, true, nil
+			return types.PathConfig{}, fmt.Errorf("unknown path option %q", key)
+		}
+	}
+
+	return pc, nil
+}
+
+// parseGlobList splits a ';'-separated pattern list and validates each pattern
+// so a broken glob fails at load time instead of silently matching nothing.
+func parseGlobList(value string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(value, ";") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		for _, segment := range strings.Split(filepath.ToSlash(p), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, err
+			}
 		}
+		patterns = append(patterns, p)
 	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no patterns")
+	}
+	return patterns, nil
+}
 
-	// No comma - use default behavior (backup enabled)
-	return strings.TrimSpace(line), true, nil
+// parseYesNo parses the yes/no spellings accepted for backup settings.
+func parseYesNo(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "y", "true", "1":
+		return true, true
+	case "no", "n", "false", "0":
+		return false, true
+	default:
+		return false, false
+	}
 }
 
 // ReadFolderList reads the list of paths to process from `paths.txt`.
@@ -256,23 +460,19 @@ func ReadFolderList(configDir string, log *logging.Logger) ([]types.PathConfig,
 			continue
 		}
 
-		path, backup, err := parsePathLine(line)
+		pc, err := parsePathLine(line)
 		if err != nil {
 			log.Warnf("Skipping malformed line in paths.txt: %s (error: %v)", line, err)
 			continue
 		}
 
-		isDir := true
-		fi, err := os.Stat(path)
+		pc.IsDir = true
+		fi, err := os.Stat(pc.Path)
 		if err == nil {
-			isDir = fi.IsDir()
+			pc.IsDir = fi.IsDir()
 		}
 
-		config = append(config, types.PathConfig{
-			Path:   path,
-			Backup: backup,
-			IsDir:  isDir,
-		})
+		config = append(config, pc)
 	}
 
 	return config, nil
@@ -295,6 +495,206 @@ func ReadBackupLocation(configDir string) (string, error) {
 	return path, nil
 }
 
+// parseBackupOptions reads the copy options from the [backup] section.
+//
+// Unlike [settings]/[advanced], where an unparsable value is ignored and the
+// default is used, invalid values here are errors: silently falling back could
+// turn verification off without the operator noticing.
+func parseBackupOptions(section map[string]string) (types.BackupOptions, error) {
+	opts := types.DefaultBackupOptions()
+
+	if v, ok := section["hash"]; ok && v != "" {
+		switch alg := types.HashAlgorithm(strings.ToLower(strings.TrimSpace(v))); alg {
+		case types.HashSHA256, types.HashSHA512, types.HashSHA1, types.HashMD5:
+			opts.Hash = alg
+		default:
+			return types.BackupOptions{}, fmt.Errorf("invalid hash value %q in [backup] section (use sha256, sha512, sha1, or md5)", v)
+		}
+	}
+
+	if v, ok := section["verify"]; ok && v != "" {
+		verify, ok := parseYesNo(v)
+		if !ok {
+			return types.BackupOptions{}, fmt.Errorf("invalid verify value %q in [backup] section", v)
+		}
+		opts.Verify = verify
+	}
+
+	if v, ok := section["move"]; ok && v != "" {
+		move, ok := parseYesNo(v)
+		if !ok {
+			return types.BackupOptions{}, fmt.Errorf("invalid move value %q in [backup] section", v)
+		}
+		opts.Move = move
+	}
+
+	if v, ok := section["collision"]; ok && v != "" {
+		switch policy := types.CollisionPolicy(strings.ToLower(strings.TrimSpace(v))); policy {
+		case types.CollisionVersion, types.CollisionKeep:
+			opts.Collision = policy
+		default:
+			return types.BackupOptions{}, fmt.Errorf("invalid collision value %q in [backup] section (use version or keep)", v)
+		}
+	}
+
+	if v, ok := section["retention"]; ok && v != "" {
+		retention, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || retention < 0 {
+			return types.BackupOptions{}, fmt.Errorf("invalid retention value %q in [backup] section (days, 0 = keep forever)", v)
+		}
+		opts.Retention = retention
+	}
+
+	if v, ok := section["min-free"]; ok && v != "" {
+		minFree, err := parseByteSize(v)
+		if err != nil {
+			return types.BackupOptions{}, fmt.Errorf("invalid min-free value %q in [backup] section (e.g. 20GB): %w", v, err)
+		}
+		opts.MinFree = minFree
+	}
+
+	if v, ok := section["min-free-percent"]; ok && v != "" {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), "%"), 64)
+		if err != nil || !(pct >= 0 && pct < 100) {
+			return types.BackupOptions{}, fmt.Errorf("invalid min-free-percent value %q in [backup] section (0 to below 100)", v)
+		}
+		opts.MinFreePercent = pct
+	}
+
+	if v, ok := section["mode"]; ok && v != "" {
+		switch mode := types.BackupMode(strings.ToLower(strings.TrimSpace(v))); mode {
+		case types.BackupModeFailover, types.BackupModeMirror:
+			opts.Mode = mode
+		default:
+			return types.BackupOptions{}, fmt.Errorf("invalid mode value %q in [backup] section (use failover or mirror)", v)
+		}
+	}
+
+	if v, ok := section["quorum"]; ok && v != "" {
+		quorum, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || quorum < 1 {
+			return types.BackupOptions{}, fmt.Errorf("invalid quorum value %q in [backup] section (number of copies, at least 1)", v)
+		}
+		if opts.Mode != types.BackupModeMirror {
+			return types.BackupOptions{}, fmt.Errorf("quorum in [backup] section requires mode=mirror")
+		}
+		opts.Quorum = quorum
+	}
+
+	if v, ok := section["compress"]; ok && v != "" {
+		c, err := parseCompression(v)
+		if err != nil {
+			return types.BackupOptions{}, fmt.Errorf("invalid compress value %q in [backup] section (use gzip or none)", v)
+		}
+		opts.Compress = c
+	}
+
+	if v, ok := section["compress-level"]; ok && v != "" {
+		level, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || level < 1 || level > 9 {
+			return types.BackupOptions{}, fmt.Errorf("invalid compress-level value %q in [backup] section (1 = fastest to 9 = smallest)", v)
+		}
+		opts.CompressLevel = level
+	}
+
+	// compress-ratio only feeds the space estimate, so it is accepted even
+	// when compression is enabled per path rather than here.
+	if v, ok := section["compress-ratio"]; ok && v != "" {
+		ratio, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(v), ":1"), 64)
+		if err != nil || !(ratio >= 1) || math.IsInf(ratio, 0) {
+			return types.BackupOptions{}, fmt.Errorf("invalid compress-ratio value %q in [backup] section (e.g. 4 or 4:1, at least 1)", v)
+		}
+		opts.CompressRatio = ratio
+	}
+
+	if v, ok := section["layout"]; ok && v != "" {
+		switch layout := types.BackupLayout(strings.ToLower(strings.TrimSpace(v))); layout {
+		case types.LayoutDated, types.LayoutObjects, types.LayoutArchive:
+			opts.Layout = layout
+		default:
+			return types.BackupOptions{}, fmt.Errorf("invalid layout value %q in [backup] section (use dated, objects, or archive)", v)
+		}
+	}
+	if v, ok := section["durability"]; ok && v != "" {
+		switch durability := types.Durability(strings.ToLower(strings.TrimSpace(v))); durability {
+		case types.DurabilityFsync, types.DurabilityNone:
+			opts.Durability = durability
+		default:
+			return types.BackupOptions{}, fmt.Errorf("invalid durability value %q in [backup] section (use fsync or none)", v)
+		}
+	}
+	if v, ok := section["archive"]; ok && v != "" {
+		switch format := types.ArchiveFormat(strings.ToLower(strings.TrimSpace(v))); format {
+		case types.ArchiveZip, types.ArchiveTar, types.ArchiveTarGz:
+			opts.Archive = format
+		default:
+			return types.BackupOptions{}, fmt.Errorf("invalid archive value %q in [backup] section (use zip, tar, or tar.gz)", v)
+		}
+		if opts.Layout != types.LayoutArchive {
+			return types.BackupOptions{}, fmt.Errorf("archive in [backup] section requires layout=archive")
+		}
+	}
+	// Archives are compressed by their format, and encrypted archives could
+	// no longer be opened in Explorer.
+	if opts.Layout == types.LayoutArchive {
+		if opts.Compress == types.CompressGzip {
+			return types.BackupOptions{}, fmt.Errorf("compress in [backup] section does not apply to layout=archive (use archive=zip or tar.gz)")
+		}
+		if v := strings.TrimSpace(section["encrypt-key"]); v != "" {
+			return types.BackupOptions{}, fmt.Errorf("encrypt-key in [backup] section is not supported with layout=archive")
+		}
+	}
+	// Objects are named by checksum, so two different files must never share
+	// one: only collision-resistant hashes may address them.
+	if opts.Layout == types.LayoutObjects && opts.Hash != types.HashSHA256 && opts.Hash != types.HashSHA512 {
+		return types.BackupOptions{}, fmt.Errorf("layout=objects in [backup] section requires hash=sha256 or sha512")
+	}
+
+	// The key itself is loaded by ReadAllConfig, which knows the config
+	// directory relative paths are resolved against.
+	if v, ok := section["encrypt-key"]; ok && strings.TrimSpace(v) != "" {
+		opts.EncryptKeyFile = strings.TrimSpace(v)
+	}
+
+	return opts, nil
+}
+
+// parseCompression parses a compress= value from [backup] or [paths].
+func parseCompression(value string) (types.Compression, error) {
+	switch c := types.Compression(strings.ToLower(strings.TrimSpace(value))); c {
+	case types.CompressNone, types.CompressGzip:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid compress value %q (use gzip or none)", value)
+}
+
+// parseBackupDirs splits a [backup] path value into its destinations.
+//
+// Several destinations are separated by ';', e.g.
+// path=\\nas1\backups; \\nas2\backups. Empty entries are dropped, and listing
+// the same destination twice is an error because a mirror to itself is not a
+// second copy.
+func parseBackupDirs(value string) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		dir := strings.TrimSpace(part)
+		if dir == "" {
+			continue
+		}
+		key := filepath.Clean(dir)
+		if seen[key] {
+			return nil, fmt.Errorf("backup destination %q is listed twice in [backup] path", dir)
+		}
+		seen[key] = true
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("missing 'path' key in [backup] section")
+	}
+	return dirs, nil
+}
+
 // parseRuntimeSettings parses [settings] and [advanced] as runtime overrides.
 func parseRuntimeSettings(sections map[string]map[string]string) types.RuntimeConfigOverrides {
 	var cfg types.RuntimeConfigOverrides
@@ -353,6 +753,121 @@ func parseRuntimeSettings(sections map[string]map[string]string) types.RuntimeCo
 	return cfg
 }
 
+// parseThrottleOptions parses the throttle keys of [advanced]:
+//
+//	max-bandwidth=20MB/s       bytes per second, as for min-free; "/s" is optional
+//	max-ops-per-second=200     file operations per second
+//	full-speed=22:00-06:00     daily windows without limits, comma-separated
+//
+// Unlike the other [advanced] keys, an invalid value is an error rather than
+// being ignored: a typo would otherwise let a run saturate a link it was
+// meant to share.
+func parseThrottleOptions(section map[string]string) (types.ThrottleOptions, error) {
+	var opts types.ThrottleOptions
+
+	if v, ok := section["max-bandwidth"]; ok && strings.TrimSpace(v) != "" {
+		value := strings.TrimSpace(v)
+		if lower := strings.ToLower(value); strings.HasSuffix(lower, "/s") {
+			value = value[:len(value)-2]
+		}
+		n, err := parseByteSize(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid max-bandwidth value %q in [advanced] section: %w", v, err)
+		}
+		opts.MaxBandwidth = n
+	}
+
+	if v, ok := section["max-ops-per-second"]; ok && strings.TrimSpace(v) != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("invalid max-ops-per-second value %q in [advanced] section", v)
+		}
+		opts.MaxOpsPerSecond = n
+	}
+
+	if v, ok := section["full-speed"]; ok && strings.TrimSpace(v) != "" {
+		for _, part := range strings.Split(v, ",") {
+			w, err := parseTimeWindow(part)
+			if err != nil {
+				return opts, fmt.Errorf("invalid full-speed value %q in [advanced] section: %w", v, err)
+			}
+			opts.FullSpeed = append(opts.FullSpeed, w)
+		}
+	}
+
+	return opts, nil
+}
+
+// parseTimeWindow parses a daily window such as 22:00-06:00 (24-hour clock).
+func parseTimeWindow(value string) (types.TimeWindow, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return types.TimeWindow{}, fmt.Errorf("%q is not a HH:MM-HH:MM window", strings.TrimSpace(value))
+	}
+	s, err := parseClock(start)
+	if err != nil {
+		return types.TimeWindow{}, err
+	}
+	e, err := parseClock(end)
+	if err != nil {
+		return types.TimeWindow{}, err
+	}
+	if s == e {
+		return types.TimeWindow{}, fmt.Errorf("window %q is empty", strings.TrimSpace(value))
+	}
+	return types.TimeWindow{Start: s, End: e}, nil
+}
+
+// parseClock parses a time of day such as 06:00 as an offset from midnight.
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", strings.TrimSpace(value))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// byteSizeUnits maps size suffixes to their multiplier. KB/MB/GB/TB use 1024
+// steps, matching how Windows Explorer reports drive sizes.
+var byteSizeUnits = []struct {
+	suffix string
+	mult   uint64
+}{
+	// Longest suffixes first so "GB" is not read as "B".
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// parseByteSize parses sizes such as 20GB, 512MB, 1.5TB, or a plain number of
+// bytes. Units are case-insensitive.
+func parseByteSize(value string) (uint64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	mult := uint64(1)
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(value, u.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(n >= 0) { // also rejects NaN
+		return 0, fmt.Errorf("not a size")
+	}
+	bytes := n * float64(mult)
+	if bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("size too large")
+	}
+	return uint64(bytes), nil
+}
+
 // parseDurationValue accepts Go duration strings such as 55m or 50ms. For
 // backward compatibility with older config files, plain numbers are interpreted
 // as milliseconds.
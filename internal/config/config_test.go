@@ -0,0 +1,469 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/logging"
+	"file-maintenance/internal/types"
+)
+
+func newTestLogger(t *testing.T) *logging.Logger {
+	t.Helper()
+
+	log, err := logging.New(t.TempDir(), logging.LogSettings{NoLogs: true})
+	if err != nil {
+		t.Fatalf("logging.New failed: %v", err)
+	}
+	return log
+}
+
+func TestParsePathLine_Table(t *testing.T) {
+	tests := []struct {
+		name           string
+		line           string
+		wantPath       string
+		wantBackup     bool
+		wantDays       *int
+		wantRetries    *int
+		wantCooldown   *time.Duration
+		wantMaxFiles   *int
+		wantInclude    []string
+		wantExclude    []string
+		wantAge        string
+		wantAgePattern string
+		wantDest       string
+		wantCompress   types.Compression
+		wantErr        bool
+	}{
+		{name: "path only defaults to backup", line: `C:\Temp\old`, wantPath: `C:\Temp\old`, wantBackup: true},
+		{name: "legacy yes", line: `C:\Temp\old, yes`, wantPath: `C:\Temp\old`, wantBackup: true},
+		{name: "legacy no", line: `C:\Temp\old, no`, wantPath: `C:\Temp\old`, wantBackup: false},
+		{name: "legacy unknown keeps backup", line: `C:\Temp\old, maybe`, wantPath: `C:\Temp\old`, wantBackup: true},
+		{
+			name:        "key-value overrides",
+			line:        `C:\Temp\scans, backup=no, days=30, retries=5, cooldown=50ms, max-files=100`,
+			wantPath:    `C:\Temp\scans`,
+			wantBackup:  false,
+			wantDays:    intPtr(30),
+			wantRetries: intPtr(5),
+			wantCooldown: func() *time.Duration {
+				d := 50 * time.Millisecond
+				return &d
+			}(),
+			wantMaxFiles: intPtr(100),
+		},
+		{name: "legacy flag with overrides", line: `\\srv\scans, no, days=0`, wantPath: `\\srv\scans`, wantBackup: false, wantDays: intPtr(0)},
+		{
+			name:        "include and exclude lists",
+			line:        `C:\Logs, include=*.log; *.tmp, exclude=**/keep/**;*.db`,
+			wantPath:    `C:\Logs`,
+			wantBackup:  true,
+			wantInclude: []string{"*.log", "*.tmp"},
+			wantExclude: []string{"**/keep/**", "*.db"},
+		},
+		{name: "age basis", line: `C:\Exports, age=birth`, wantPath: `C:\Exports`, wantBackup: true, wantAge: "birth"},
+		{name: "age from file name", line: `D:\Camera, age=name:IMG_{yyyyMMdd}`, wantPath: `D:\Camera`, wantBackup: true, wantAge: "name", wantAgePattern: "IMG_{yyyyMMdd}"},
+		{name: "age name without pattern", line: `D:\Camera, age=name`, wantErr: true},
+		{name: "unknown age basis", line: `D:\Camera, age=yesterday`, wantErr: true},
+		{name: "invalid prune-empty-dirs", line: `D:\Scans, prune-empty-dirs=maybe`, wantErr: true},
+		{name: "prune-min-age needs a unit", line: `D:\Scans, prune-empty-dirs=yes, prune-min-age=7`, wantErr: true},
+		{name: "per-path dest", line: `\\srv\scans, backup=yes, dest=\\nas2\scan-archive`, wantPath: `\\srv\scans`, wantBackup: true, wantDest: `\\nas2\scan-archive`},
+		{name: "dest with several roots", line: `\\srv\scans, dest=\\nas1\a;\\nas2\b`, wantErr: true},
+		{name: "empty dest", line: `\\srv\scans, dest=`, wantErr: true},
+		{name: "compress gzip", line: `D:\exports, compress=GZIP`, wantPath: `D:\exports`, wantBackup: true, wantCompress: types.CompressGzip},
+		{name: "compress none", line: `D:\exports, compress=none`, wantPath: `D:\exports`, wantBackup: true, wantCompress: types.CompressNone},
+		{name: "unknown compress", line: `D:\exports, compress=zstd`, wantErr: true},
+		{name: "empty path", line: `, yes`, wantErr: true},
+		{name: "unknown key", line: `C:\Temp\old, dayz=30`, wantErr: true},
+		{name: "invalid days", line: `C:\Temp\old, days=abc`, wantErr: true},
+		{name: "negative retries", line: `C:\Temp\old, retries=-1`, wantErr: true},
+		{name: "invalid backup value", line: `C:\Temp\old, backup=sometimes`, wantErr: true},
+		{name: "invalid glob", line: `C:\Temp\old, include=[*.log`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePathLine(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (got=%+v)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Path != tt.wantPath || got.Backup != tt.wantBackup {
+				t.Fatalf("want path=%q backup=%v, got path=%q backup=%v", tt.wantPath, tt.wantBackup, got.Path, got.Backup)
+			}
+			assertIntPtr(t, "days", tt.wantDays, got.Days)
+			assertIntPtr(t, "retries", tt.wantRetries, got.Retries)
+			assertIntPtr(t, "max-files", tt.wantMaxFiles, got.MaxFiles)
+			if strings.Join(got.Include, "|") != strings.Join(tt.wantInclude, "|") || strings.Join(got.Exclude, "|") != strings.Join(tt.wantExclude, "|") {
+				t.Fatalf("want include=%v exclude=%v, got include=%v exclude=%v", tt.wantInclude, tt.wantExclude, got.Include, got.Exclude)
+			}
+			if string(got.Age) != tt.wantAge || got.AgeNamePattern != tt.wantAgePattern {
+				t.Fatalf("want age=%q pattern=%q, got age=%q pattern=%q", tt.wantAge, tt.wantAgePattern, got.Age, got.AgeNamePattern)
+			}
+			if (tt.wantCooldown == nil) != (got.Cooldown == nil) || (tt.wantCooldown != nil && *tt.wantCooldown != *got.Cooldown) {
+				t.Fatalf("want cooldown %v, got %v", tt.wantCooldown, got.Cooldown)
+			}
+			if got.Dest != tt.wantDest {
+				t.Fatalf("want dest %q, got %q", tt.wantDest, got.Dest)
+			}
+			if got.Compress != tt.wantCompress {
+				t.Fatalf("want compress %q, got %q", tt.wantCompress, got.Compress)
+			}
+		})
+	}
+}
+
+func TestParsePathLine_PruneEmptyDirs(t *testing.T) {
+	got, err := parsePathLine(`D:\Scans, prune-empty-dirs=yes, prune-min-age=72h`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.PruneEmptyDirs {
+		t.Fatalf("expected PruneEmptyDirs to be set")
+	}
+	if got.PruneMinAge == nil || *got.PruneMinAge != 72*time.Hour {
+		t.Fatalf("want prune-min-age 72h, got %v", got.PruneMinAge)
+	}
+
+	got, err = parsePathLine(`D:\Scans, yes`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.PruneEmptyDirs || got.PruneMinAge != nil {
+		t.Fatalf("expected pruning off by default, got %+v", got)
+	}
+}
+
+func TestReadAllConfig_PathOptions(t *testing.T) {
+	dir := t.TempDir()
+	content := "[backup]\npath=D:\\backups\n\n[paths]\nC:\\Temp\\old, yes\nC:\\Temp\\scans, backup=yes, days=30, retries=5\n\n[settings]\ndays=7\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.ini"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.ini: %v", err)
+	}
+
+	plan, runtime, err := ReadAllConfig(dir, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("ReadAllConfig: %v", err)
+	}
+
+	if len(plan.Paths) != 2 {
+		t.Fatalf("expected 2 paths, got %d: %+v", len(plan.Paths), plan.Paths)
+	}
+	if plan.Paths[0].Days != nil {
+		t.Fatalf("expected no days override on first path, got %d", *plan.Paths[0].Days)
+	}
+	assertIntPtr(t, "days", intPtr(30), plan.Paths[1].Days)
+	assertIntPtr(t, "retries", intPtr(5), plan.Paths[1].Retries)
+	assertIntPtr(t, "global days", intPtr(7), runtime.Days)
+}
+
+func TestReadAllConfig_BackupDestinations(t *testing.T) {
+	tests := []struct {
+		name     string
+		backup   string
+		wantDirs []string
+		wantErr  bool
+	}{
+		{name: "single", backup: "path=D:\\backups", wantDirs: []string{`D:\backups`}},
+		{name: "several", backup: "path=D:\\backups; \\\\nas\\backups ;\nmode=mirror\nquorum=2", wantDirs: []string{`D:\backups`, `\\nas\backups`}},
+		{name: "quorum above destinations", backup: "path=D:\\backups;E:\\backups\nmode=mirror\nquorum=3", wantErr: true},
+		{name: "duplicate destination", backup: "path=D:\\backups;D:\\backups", wantErr: true},
+		{name: "only separators", backup: "path=;;", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			content := "[backup]\n" + tt.backup + "\n\n[paths]\nC:\\Temp\\old, yes\n"
+			if err := os.WriteFile(filepath.Join(dir, "config.ini"), []byte(content), 0o644); err != nil {
+				t.Fatalf("write config.ini: %v", err)
+			}
+
+			plan, _, err := ReadAllConfig(dir, newTestLogger(t))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (dirs=%q)", plan.BackupDirs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadAllConfig: %v", err)
+			}
+			if !reflect.DeepEqual(plan.BackupDirs, tt.wantDirs) || plan.BackupDir != tt.wantDirs[0] {
+				t.Fatalf("want dirs %q, got BackupDir=%q BackupDirs=%q", tt.wantDirs, plan.BackupDir, plan.BackupDirs)
+			}
+		})
+	}
+}
+
+func TestLoadBackupKey_Table(t *testing.T) {
+	raw := bytes.Repeat([]byte{0xab}, 32)
+	hexKey := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name     string
+		contents []byte
+		mode     os.FileMode
+		wantErr  bool
+	}{
+		{name: "hex with newline", contents: []byte(hexKey + "\n"), mode: 0o600},
+		{name: "raw bytes", contents: raw, mode: 0o600},
+		{name: "short key", contents: []byte(strings.Repeat("ab", 16)), mode: 0o600, wantErr: true},
+		{name: "not hex", contents: []byte(strings.Repeat("zz", 32)), mode: 0o600, wantErr: true},
+		{name: "readable by others", contents: []byte(hexKey), mode: 0o644, wantErr: runtime.GOOS != "windows"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p := filepath.Join(dir, "backup.key")
+			if err := os.WriteFile(p, tt.contents, tt.mode); err != nil {
+				t.Fatalf("write key: %v", err)
+			}
+			if err := os.Chmod(p, tt.mode); err != nil {
+				t.Fatalf("chmod key: %v", err)
+			}
+
+			// Relative paths resolve against the config directory.
+			key, err := loadBackupKey(dir, "backup.key")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got key %s", key.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !bytes.Equal(key.Key, raw) {
+				t.Fatalf("want key %x, got %x", raw, key.Key)
+			}
+			if len(key.ID) != 16 {
+				t.Fatalf("want a 16-character key ID, got %q", key.ID)
+			}
+		})
+	}
+}
+
+func TestReadAllConfig_EncryptKey(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "backup.key"), []byte(strings.Repeat("01", 32)), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	content := "[backup]\npath=D:\\backups\nencrypt-key=backup.key\n\n[paths]\nC:\\Temp\\old, yes\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.ini"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write config.ini: %v", err)
+	}
+
+	plan, _, err := ReadAllConfig(dir, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("ReadAllConfig: %v", err)
+	}
+	if plan.Backup.Key == nil || plan.Backup.Key.ID == "" {
+		t.Fatalf("expected the key to be loaded, got %+v", plan.Backup)
+	}
+
+	// A missing key file stops the run instead of writing unencrypted backups.
+	if err := os.Remove(filepath.Join(dir, "backup.key")); err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+	if _, _, err := ReadAllConfig(dir, newTestLogger(t)); err == nil {
+		t.Fatalf("expected error for missing key file")
+	}
+}
+
+func TestParseBackupOptions_Table(t *testing.T) {
+	tests := []struct {
+		name    string
+		section map[string]string
+		want    types.BackupOptions
+		wantErr bool
+	}{
+		{name: "defaults", section: map[string]string{"path": `D:\backups`}, want: types.DefaultBackupOptions()},
+		{name: "sha512 without verify", section: map[string]string{"hash": "SHA512", "verify": "no"}, want: types.BackupOptions{Hash: types.HashSHA512, Verify: false, Collision: types.CollisionVersion, Move: true}},
+		{name: "md5", section: map[string]string{"hash": "md5"}, want: types.BackupOptions{Hash: types.HashMD5, Verify: true, Collision: types.CollisionVersion, Move: true}},
+		{name: "keep on collision", section: map[string]string{"collision": "Keep"}, want: types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionKeep, Move: true}},
+		{name: "unknown collision", section: map[string]string{"collision": "overwrite"}, wantErr: true},
+		{name: "retention", section: map[string]string{"retention": "90"}, want: types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, Retention: 90}},
+		{name: "negative retention", section: map[string]string{"retention": "-1"}, wantErr: true},
+		{name: "non-numeric retention", section: map[string]string{"retention": "90d"}, wantErr: true},
+		{name: "unknown hash", section: map[string]string{"hash": "crc32"}, wantErr: true},
+		{name: "invalid verify", section: map[string]string{"verify": "sometimes"}, wantErr: true},
+		{
+			name:    "free-space reserve",
+			section: map[string]string{"min-free": "20GB", "min-free-percent": "10%"},
+			want:    types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, MinFree: 20 << 30, MinFreePercent: 10},
+		},
+		{name: "invalid min-free", section: map[string]string{"min-free": "lots"}, wantErr: true},
+		{name: "min-free-percent of 100", section: map[string]string{"min-free-percent": "100"}, wantErr: true},
+		{
+			name:    "mirror with quorum",
+			section: map[string]string{"mode": "Mirror", "quorum": "2"},
+			want:    types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, Mode: types.BackupModeMirror, Quorum: 2},
+		},
+		{name: "unknown mode", section: map[string]string{"mode": "raid"}, wantErr: true},
+		{name: "quorum without mirror", section: map[string]string{"quorum": "1"}, wantErr: true},
+		{name: "zero quorum", section: map[string]string{"mode": "mirror", "quorum": "0"}, wantErr: true},
+		{
+			name:    "gzip with level and ratio",
+			section: map[string]string{"compress": "gzip", "compress-level": "9", "compress-ratio": "4:1"},
+			want:    types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, Compress: types.CompressGzip, CompressLevel: 9, CompressRatio: 4},
+		},
+		{name: "unknown compress", section: map[string]string{"compress": "zip"}, wantErr: true},
+		{name: "compress-level out of range", section: map[string]string{"compress": "gzip", "compress-level": "10"}, wantErr: true},
+		{name: "compress-ratio below 1", section: map[string]string{"compress-ratio": "0.5"}, wantErr: true},
+		{
+			name:    "encrypt-key",
+			section: map[string]string{"encrypt-key": "keys/backup.key"},
+			want:    types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, EncryptKeyFile: "keys/backup.key"},
+		},
+		{
+			name:    "objects layout",
+			section: map[string]string{"layout": "Objects", "hash": "sha512"},
+			want:    types.BackupOptions{Hash: types.HashSHA512, Verify: true, Collision: types.CollisionVersion, Move: true, Layout: types.LayoutObjects},
+		},
+		{name: "unknown layout", section: map[string]string{"layout": "tree"}, wantErr: true},
+		{name: "objects layout with md5", section: map[string]string{"layout": "objects", "hash": "md5"}, wantErr: true},
+		{
+			name:    "archive layout",
+			section: map[string]string{"layout": "archive", "archive": "TAR.GZ"},
+			want:    types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, Layout: types.LayoutArchive, Archive: types.ArchiveTarGz},
+		},
+		{name: "move disabled", section: map[string]string{"move": "no"}, want: types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion}},
+		{name: "invalid move", section: map[string]string{"move": "sometimes"}, wantErr: true},
+		{name: "durability none", section: map[string]string{"durability": "None"}, want: types.BackupOptions{Hash: types.HashSHA256, Verify: true, Collision: types.CollisionVersion, Move: true, Durability: types.DurabilityNone}},
+		{name: "invalid durability", section: map[string]string{"durability": "sync"}, wantErr: true},
+		{name: "unknown archive", section: map[string]string{"layout": "archive", "archive": "rar"}, wantErr: true},
+		{name: "archive without archive layout", section: map[string]string{"archive": "zip"}, wantErr: true},
+		{name: "archive layout with compress", section: map[string]string{"layout": "archive", "compress": "gzip"}, wantErr: true},
+		{name: "archive layout with encrypt-key", section: map[string]string{"layout": "archive", "encrypt-key": "backup.key"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBackupOptions(tt.section)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (got=%+v)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseByteSize_Table(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "1048576", want: 1 << 20},
+		{in: "512B", want: 512},
+		{in: "20GB", want: 20 << 30},
+		{in: "20 gb", want: 20 << 30},
+		{in: "1.5TB", want: 3 << 39},
+		{in: "64MiB", want: 64 << 20},
+		{in: "2g", want: 2 << 30},
+		{in: "", wantErr: true},
+		{in: "GB", wantErr: true},
+		{in: "-1GB", wantErr: true},
+		{in: "20 gigabytes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseByteSize(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseThrottleOptions_Table(t *testing.T) {
+	night := types.TimeWindow{Start: 22 * time.Hour, End: 6 * time.Hour}
+	lunch := types.TimeWindow{Start: 12 * time.Hour, End: 13*time.Hour + 30*time.Minute}
+
+	tests := []struct {
+		name    string
+		section map[string]string
+		want    types.ThrottleOptions
+		wantErr bool
+	}{
+		{name: "none", section: nil, want: types.ThrottleOptions{}},
+		{name: "bandwidth per second", section: map[string]string{"max-bandwidth": "20MB/s"}, want: types.ThrottleOptions{MaxBandwidth: 20 << 20}},
+		{name: "bandwidth without /s", section: map[string]string{"max-bandwidth": "512k"}, want: types.ThrottleOptions{MaxBandwidth: 512 << 10}},
+		{name: "ops", section: map[string]string{"max-ops-per-second": "200"}, want: types.ThrottleOptions{MaxOpsPerSecond: 200}},
+		{
+			name:    "full-speed windows",
+			section: map[string]string{"max-bandwidth": "1MB/s", "full-speed": "22:00-06:00, 12:00-13:30"},
+			want:    types.ThrottleOptions{MaxBandwidth: 1 << 20, FullSpeed: []types.TimeWindow{night, lunch}},
+		},
+		{name: "invalid bandwidth", section: map[string]string{"max-bandwidth": "fast"}, wantErr: true},
+		{name: "negative ops", section: map[string]string{"max-ops-per-second": "-5"}, wantErr: true},
+		{name: "window without end", section: map[string]string{"full-speed": "22:00"}, wantErr: true},
+		{name: "invalid clock", section: map[string]string{"full-speed": "22:00-25:00"}, wantErr: true},
+		{name: "empty window", section: map[string]string{"full-speed": "06:00-06:00"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseThrottleOptions(tt.section)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("want %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func assertIntPtr(t *testing.T, name string, want, got *int) {
+	t.Helper()
+	if (want == nil) != (got == nil) || (want != nil && *want != *got) {
+		t.Fatalf("want %s %v, got %v", name, derefInt(want), derefInt(got))
+	}
+}
+
+func derefInt(v *int) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,190 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// errAgeBasisUnsupported is returned when the current OS does not expose the
+// requested timestamp (for example change time on Windows).
+var errAgeBasisUnsupported = errors.New("age basis not supported on this platform")
+
+// ageEvaluator decides whether a file is old enough for one configured path.
+//
+// Design notes:
+//   - The evaluator is built once per path, before walking, so an invalid or
+//     unsupported setting skips that path instead of failing for every file.
+//   - Only AgeModified can be answered from os.FileInfo alone on every OS; the
+//     other stat-based bases read OS-specific data (see filetime_<os>.go).
+type ageEvaluator struct {
+	basis types.AgeBasis
+	names *nameDatePattern
+}
+
+// newAgeEvaluator validates basis/pattern and returns an evaluator for them.
+func newAgeEvaluator(basis types.AgeBasis, pattern string) (ageEvaluator, error) {
+	if basis == "" {
+		basis = types.AgeModified
+	}
+
+	switch basis {
+	case types.AgeModified:
+		return ageEvaluator{basis: basis}, nil
+	case types.AgeChanged, types.AgeAccessed, types.AgeCreated:
+		if !ageBasisSupported(basis) {
+			return ageEvaluator{}, fmt.Errorf("%s: %w", basis, errAgeBasisUnsupported)
+		}
+		return ageEvaluator{basis: basis}, nil
+	case types.AgeFileName:
+		names, err := compileNameDatePattern(pattern)
+		if err != nil {
+			return ageEvaluator{}, err
+		}
+		return ageEvaluator{basis: basis, names: names}, nil
+	default:
+		return ageEvaluator{}, fmt.Errorf("unknown age basis %q", basis)
+	}
+}
+
+// timestamp returns the time used for age decisions.
+//
+// ok is false when the basis is AgeFileName and the name carries no date; such
+// files are never considered old.
+func (a ageEvaluator) timestamp(path string, info os.FileInfo) (t time.Time, ok bool, err error) {
+	switch a.basis {
+	case types.AgeFileName:
+		t, ok = a.names.parse(filepath.Base(path))
+		return t, ok, nil
+	case types.AgeChanged, types.AgeAccessed, types.AgeCreated:
+		t, err = statTime(path, info, a.basis)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return t, true, nil
+	default:
+		return info.ModTime(), true, nil
+	}
+}
+
+// isOlder reports whether the file's selected timestamp is strictly before
+// now - days (same cutoff rules as IsFileOlder).
+func (a ageEvaluator) isOlder(path string, info os.FileInfo, days int) (bool, error) {
+	t, ok, err := a.timestamp(path, info)
+	if err != nil || !ok {
+		return false, err
+	}
+	return isTimeOlder(t, days), nil
+}
+
+// nameDatePattern extracts a date from a file name.
+//
+// Pattern syntax: literal text with one or more {...} groups containing date
+// tokens. Supported tokens: yyyy, yy, MM, dd, HH, mm, ss. Any other character
+// inside a group must appear literally, e.g. "IMG_{yyyyMMdd}",
+// "scan-{yyyy-MM-dd}", or "{yyyyMMdd}_{HHmmss}".
+//
+// The pattern may match anywhere in the base name. Dates are interpreted in
+// local time.
+type nameDatePattern struct {
+	re     *regexp.Regexp
+	layout string
+}
+
+var nameDateTokens = []struct {
+	token  string
+	layout string
+	digits int
+}{
+	{"yyyy", "2006", 4},
+	{"yy", "06", 2},
+	{"MM", "01", 2},
+	{"dd", "02", 2},
+	{"HH", "15", 2},
+	{"mm", "04", 2},
+	{"ss", "05", 2},
+}
+
+// compileNameDatePattern turns a pattern like "IMG_{yyyyMMdd}" into a regexp
+// and a matching time layout.
+func compileNameDatePattern(pattern string) (*nameDatePattern, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("age=name requires a pattern, e.g. age=name:IMG_{yyyyMMdd}")
+	}
+
+	var (
+		expr   strings.Builder
+		layout strings.Builder
+		groups int
+	)
+
+	rest := pattern
+	for rest != "" {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			expr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		expr.WriteString(regexp.QuoteMeta(rest[:open]))
+
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated { in name pattern %q", pattern)
+		}
+		group := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+
+		expr.WriteString("(")
+		for group != "" {
+			matched := false
+			for _, tok := range nameDateTokens {
+				if strings.HasPrefix(group, tok.token) {
+					fmt.Fprintf(&expr, `\d{%d}`, tok.digits)
+					layout.WriteString(tok.layout)
+					group = group[len(tok.token):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				expr.WriteString(regexp.QuoteMeta(group[:1]))
+				layout.WriteString(group[:1])
+				group = group[1:]
+			}
+		}
+		expr.WriteString(")")
+		groups++
+	}
+
+	if groups == 0 {
+		return nil, fmt.Errorf("name pattern %q has no {date} group", pattern)
+	}
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("compile name pattern %q: %w", pattern, err)
+	}
+
+	return &nameDatePattern{re: re, layout: layout.String()}, nil
+}
+
+// parse returns the date embedded in name, if the pattern matches and the
+// captured text is a valid date.
+func (p *nameDatePattern) parse(name string) (time.Time, bool) {
+	m := p.re.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, false
+	}
+
+	t, err := time.ParseInLocation(p.layout, strings.Join(m[1:], ""), time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
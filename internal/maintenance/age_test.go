@@ -0,0 +1,113 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestNameDatePattern_Table(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		fileName string
+		want     time.Time
+		wantOK   bool
+	}{
+		{"camera export", "IMG_{yyyyMMdd}", "IMG_20240131_0042.jpg", time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local), true},
+		{"dashed date anywhere", "{yyyy-MM-dd}", "report 2023-07-04 final.pdf", time.Date(2023, 7, 4, 0, 0, 0, 0, time.Local), true},
+		{"date and time groups", "scan_{yyyyMMdd}_{HHmmss}", "scan_20220102_030405.tif", time.Date(2022, 1, 2, 3, 4, 5, 0, time.Local), true},
+		{"two digit year", "log{yyMMdd}", "log991231.txt", time.Date(1999, 12, 31, 0, 0, 0, 0, time.Local), true},
+		{"literal prefix must match", "IMG_{yyyyMMdd}", "DSC_20240131.jpg", time.Time{}, false},
+		{"invalid calendar date", "IMG_{yyyyMMdd}", "IMG_20241345.jpg", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := compileNameDatePattern(tt.pattern)
+			if err != nil {
+				t.Fatalf("compile: %v", err)
+			}
+			got, ok := p.parse(tt.fileName)
+			if ok != tt.wantOK {
+				t.Fatalf("want ok=%v, got %v (t=%v)", tt.wantOK, ok, got)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Fatalf("want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompileNameDatePattern_Errors(t *testing.T) {
+	for _, pattern := range []string{"", "IMG_", "IMG_{yyyyMMdd"} {
+		if _, err := compileNameDatePattern(pattern); err == nil {
+			t.Fatalf("expected error for pattern %q", pattern)
+		}
+	}
+}
+
+func TestAgeEvaluator_AccessTime(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "restored.dat")
+	mustWriteFile(t, p, "x")
+
+	// Old access time, fresh modification time.
+	now := time.Now()
+	if err := os.Chtimes(p, now.AddDate(0, 0, -30), now); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+
+	byMtime, err := newAgeEvaluator(types.AgeModified, "")
+	if err != nil {
+		t.Fatalf("mtime evaluator: %v", err)
+	}
+	if old, _ := byMtime.isOlder(p, info, 7); old {
+		t.Fatalf("expected file to be recent by mtime")
+	}
+
+	byAtime, err := newAgeEvaluator(types.AgeAccessed, "")
+	if err != nil {
+		t.Skipf("atime not supported here: %v", err)
+	}
+	old, err := byAtime.isOlder(p, info, 7)
+	if err != nil {
+		t.Fatalf("atime check: %v", err)
+	}
+	if !old {
+		t.Fatalf("expected file to be old by atime")
+	}
+}
+
+func TestWorker_Integration_FileNameAgeBasis(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	// All files have a fresh mtime; only the name date decides.
+	oldByName := filepath.Join(src, "IMG_20200101_0001.jpg")
+	newByName := filepath.Join(src, "IMG_"+time.Now().Format("20060102")+"_0002.jpg")
+	noDate := filepath.Join(src, "notes.txt")
+	for _, p := range []string{oldByName, newByName, noDate} {
+		mustWriteFile(t, p, "x")
+	}
+
+	pathconfig := []types.PathConfig{
+		{Path: src, Backup: false, IsDir: true, Age: types.AgeFileName, AgeNamePattern: "IMG_{yyyyMMdd}"},
+	}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	assertNotExists(t, oldByName)
+	assertExists(t, newByName)
+	assertExists(t, noDate)
+}
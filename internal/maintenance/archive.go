@@ -0,0 +1,373 @@
+package maintenance
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// archiveEntryOverhead is the space reserved per file for archive headers in
+// batch space checks ([backup] layout=archive). A tar header plus padding is at
+// most 1 KiB; zip local and central directory entries are smaller.
+const archiveEntryOverhead = 1024
+
+// archiveExt returns the file extension for archives of the given format.
+func archiveExt(format types.ArchiveFormat) string {
+	switch format {
+	case types.ArchiveTar:
+		return ".tar"
+	case types.ArchiveTarGz:
+		return ".tar.gz"
+	default:
+		return ".zip"
+	}
+}
+
+// archiveWriter adds files to one archive. close finishes the archive format
+// (zip central directory, tar trailer, gzip footer) but not the file below it.
+type archiveWriter interface {
+	add(name string, modTime time.Time, size int64, r io.Reader) error
+	close() error
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipArchiveWriter) add(name string, modTime time.Time, size int64, r io.Reader) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+	hdr.SetMode(0o644)
+	w, err := z.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, r, size)
+	return err
+}
+
+func (z *zipArchiveWriter) close() error {
+	return z.zw.Close()
+}
+
+// tarArchiveWriter writes a tar stream, gzip-compressed when gz is not a
+// nopWriteCloser.
+type tarArchiveWriter struct {
+	tw *tar.Writer
+	gz io.WriteCloser
+}
+
+func (t *tarArchiveWriter) add(name string, modTime time.Time, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	}
+	if err := t.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.CopyN(t.tw, r, size)
+	return err
+}
+
+func (t *tarArchiveWriter) close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	return t.gz.Close()
+}
+
+// archiveBundle is one archive being written during a run: every archived
+// file of one configured folder for one destination root.
+//
+// The archive is written to path + ".tmp" and only renamed to path by finish,
+// after it was closed and synced to disk. Files added to it must not be
+// deleted before then.
+type archiveBundle struct {
+	root   string
+	path   string
+	format types.ArchiveFormat
+
+	file *os.File
+	w    archiveWriter
+
+	// createdDirs are the folders openArchiveBundle created for the archive,
+	// synced by finish along with the archive's own folder.
+	createdDirs []string
+
+	// broken is set when writing to the archive failed. Its stream may be
+	// inconsistent, so nothing more is added and finish discards it.
+	broken error
+
+	members []archivedFile
+}
+
+// archivedFile is one file added to an archiveBundle.
+type archivedFile struct {
+	job     FileJob
+	name    string // slash-separated name inside the archive
+	sum     string
+	size    int64
+	modTime time.Time
+}
+
+// archiveBundlePath picks the archive for folderRoot under backupRoot:
+// <backupRoot>/<DDMmmYY>/<folder-name>.<ext>, or "<folder-name> (2).<ext>" and
+// so on when that name exists, is being written, or is in taken (archives
+// opened earlier in this run).
+func archiveBundlePath(backupRoot, folderRoot string, format types.ArchiveFormat, taken map[string]bool) (string, error) {
+	dir := filepath.Join(backupRoot, time.Now().Format(backupDateLayout))
+	base := filepath.Base(folderRoot)
+	ext := archiveExt(format)
+
+	for n := 1; n <= maxBackupVersions; n++ {
+		name := base + ext
+		if n > 1 {
+			name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		p := filepath.Join(dir, name)
+		if !taken[p] && !DoesFileExist(p) && !DoesFileExist(p+".tmp") {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no free archive name for %s in %s", base, dir)
+}
+
+// openArchiveBundle creates the temporary file for a new archive at path.
+// level is the compression level for zip and tar.gz (0 = default).
+func openArchiveBundle(root, path string, format types.ArchiveFormat, level int) (*archiveBundle, error) {
+	createdDirs, err := mkdirAllCreated(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	var w archiveWriter
+	switch format {
+	case types.ArchiveTar, types.ArchiveTarGz:
+		c := types.CompressNone
+		if format == types.ArchiveTarGz {
+			c = types.CompressGzip
+		}
+		gz, err := newCompressWriter(f, c, level, "")
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return nil, err
+		}
+		w = &tarArchiveWriter{tw: tar.NewWriter(gz), gz: gz}
+	default:
+		zw := zip.NewWriter(f)
+		if level != 0 {
+			zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(out, level)
+			})
+		}
+		w = &zipArchiveWriter{zw: zw}
+	}
+
+	return &archiveBundle{root: root, path: path, format: format, file: f, w: w, createdDirs: createdDirs}, nil
+}
+
+// add streams job's file into the archive under its path relative to
+// job.folderRoot, with its modification time, and returns what was archived.
+//
+// A file that changes while it is read is left in the archive but reported as
+// an error, so it is neither recorded nor deleted. The source is read through
+// ctl (for its throttle); ctl should carry no context, since an interrupted
+// write would leave the whole archive unusable.
+func (b *archiveBundle) add(job FileJob, alg types.HashAlgorithm, ctl copyControl) (archivedFile, error) {
+	if b.broken != nil {
+		return archivedFile{}, fmt.Errorf("archive %s is unusable: %w", b.path, b.broken)
+	}
+
+	rel, err := filepath.Rel(job.folderRoot, job.srcPath)
+	if err != nil {
+		return archivedFile{}, err
+	}
+	h, err := newHasher(alg)
+	if err != nil {
+		return archivedFile{}, err
+	}
+
+	in, err := os.Open(job.srcPath)
+	if err != nil {
+		return archivedFile{}, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return archivedFile{}, err
+	}
+
+	member := archivedFile{
+		job:     job,
+		name:    filepath.ToSlash(rel),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+	src := meteredReader{r: in, m: newCopyMeter(ctl, 0)}
+	if err := b.w.add(member.name, member.modTime, member.size, io.TeeReader(src, h)); err != nil {
+		b.broken = err
+		return archivedFile{}, err
+	}
+	member.sum = hex.EncodeToString(h.Sum(nil))
+
+	after, err := os.Stat(job.srcPath)
+	if err != nil {
+		return archivedFile{}, err
+	}
+	if after.Size() != member.size || !after.ModTime().Equal(member.modTime) {
+		return archivedFile{}, fmt.Errorf("source changed while it was archived")
+	}
+
+	b.members = append(b.members, member)
+	return member, nil
+}
+
+// finish completes the archive and returns the members that are safely in it.
+//
+// Order: close the archive format, fsync the file, close it, rename the
+// temporary file into place, fsync its folder and the parents of the folders
+// openArchiveBundle created. Archives are always synced, whatever [backup]
+// durability says: many sources depend on one file. With verify, the renamed
+// archive is read back and only members whose checksum matches what was read
+// from the source are returned. A broken or empty archive is removed and
+// returns no members.
+func (b *archiveBundle) finish(verify bool, alg types.HashAlgorithm) ([]archivedFile, error) {
+	tmp := b.file.Name()
+	discard := func(err error) ([]archivedFile, error) {
+		_ = b.file.Close()
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+
+	if b.broken != nil {
+		return discard(b.broken)
+	}
+	if len(b.members) == 0 {
+		return discard(nil)
+	}
+	if err := b.w.close(); err != nil {
+		return discard(err)
+	}
+	if err := syncFile(b.file); err != nil {
+		return discard(fmt.Errorf("sync archive: %w", err))
+	}
+	if err := b.file.Close(); err != nil {
+		return discard(err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return discard(err)
+	}
+	if err := syncDir(filepath.Dir(b.path)); err != nil {
+		return nil, fmt.Errorf("sync archive folder: %w", err)
+	}
+	if err := syncCreatedDirs(b.createdDirs); err != nil {
+		return nil, fmt.Errorf("sync archive folder: %w", err)
+	}
+
+	if !verify {
+		return b.members, nil
+	}
+
+	sums := make(map[string]string, len(b.members))
+	err := readArchive(b.path, b.format, func(name string, r io.Reader) error {
+		h, err := newHasher(alg)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify archive: %w", err)
+	}
+
+	verified := make([]archivedFile, 0, len(b.members))
+	for _, m := range b.members {
+		if sums[m.name] == m.sum {
+			verified = append(verified, m)
+		}
+	}
+	if len(verified) != len(b.members) {
+		return verified, fmt.Errorf("%w: %d of %d file(s) in %s", errChecksumMismatch, len(b.members)-len(verified), len(b.members), b.path)
+	}
+	return verified, nil
+}
+
+// readArchive calls fn for every regular file in the archive at path, in
+// archive order, with its slash-separated name and a reader of its content.
+func readArchive(path string, format types.ArchiveFormat, fn func(name string, r io.Reader) error) error {
+	if format == types.ArchiveZip {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			err = fn(f.Name, r)
+			_ = r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	c := types.CompressNone
+	if format == types.ArchiveTarGz {
+		c = types.CompressGzip
+	}
+	in, err := newDecompressReader(f, c)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tr := tar.NewReader(in)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestWorker_Integration_ArchiveLayout_Table(t *testing.T) {
+	tests := []struct {
+		format types.ArchiveFormat
+		ext    string
+	}{
+		{format: types.ArchiveZip, ext: ".zip"},
+		{format: types.ArchiveTar, ext: ".tar"},
+		{format: types.ArchiveTarGz, ext: ".tar.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			root, src, backup := newSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.QueueSize = 2 // several batches go into the same archive
+			cfg.Backup = types.DefaultBackupOptions()
+			cfg.Backup.Layout = types.LayoutArchive
+			cfg.Backup.Archive = tt.format
+
+			files := map[string]string{
+				filepath.Join(src, "a.txt"):                "alpha",
+				filepath.Join(src, "sub", "b.txt"):         "bravo",
+				filepath.Join(src, "sub", "deep", "c.txt"): "charlie",
+			}
+			for p, contents := range files {
+				mustMkdirAll(t, filepath.Dir(p))
+				mustWriteFile(t, p, contents)
+				mustSetAgeDays(t, p, 10)
+			}
+
+			pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+			if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+			for p := range files {
+				assertNotExists(t, p)
+			}
+
+			today := time.Now().Format(backupDateLayout)
+			archive := filepath.Join(backup, today, filepath.Base(src)+tt.ext)
+			assertExists(t, archive)
+			assertNotExists(t, archive+".tmp")
+
+			got := make(map[string]string)
+			if err := readArchive(archive, tt.format, func(name string, r io.Reader) error {
+				b, err := io.ReadAll(r)
+				got[name] = string(b)
+				return err
+			}); err != nil {
+				t.Fatalf("read archive: %v", err)
+			}
+			if len(got) != 3 || got["a.txt"] != "alpha" || got["sub/deep/c.txt"] != "charlie" {
+				t.Fatalf("unexpected archive contents: %v", got)
+			}
+
+			records, _, err := ReadBackupManifest(filepath.Join(backup, today, backupManifestName))
+			if err != nil || len(records) != len(files) {
+				t.Fatalf("read manifest: records=%d err=%v", len(records), err)
+			}
+			for _, rec := range records {
+				if rec.BackupPath != archive || rec.Archive != string(tt.format) || rec.Member == "" {
+					t.Fatalf("unexpected manifest record: %+v", rec)
+				}
+			}
+
+			// Restore extracts the members to their original places, with
+			// their modification times.
+			summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+			if err != nil || summary.Restored != len(files) || summary.Failed != 0 {
+				t.Fatalf("restore: summary=%+v err=%v", summary, err)
+			}
+			for p, contents := range files {
+				assertFileContents(t, p, contents)
+			}
+			info, err := os.Stat(filepath.Join(src, "sub", "b.txt"))
+			if err != nil || time.Since(info.ModTime()) < 9*24*time.Hour {
+				t.Fatalf("restored file lost its modification time: %v", err)
+			}
+
+			target := filepath.Join(root, "restored")
+			opts := types.RestoreOptions{Dates: today, Target: target, Match: "**/deep/**"}
+			if summary, err := Restore(pathconfig, backup, opts, log); err != nil || summary.Restored != 1 {
+				t.Fatalf("restore to target: summary=%+v err=%v", summary, err)
+			}
+			assertFileContents(t, filepath.Join(target, filepath.Base(src), "sub", "deep", "c.txt"), "charlie")
+		})
+	}
+}
+
+func TestWorker_Integration_ArchiveLayout_SecondRunGetsNewArchive(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Layout = types.LayoutArchive
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	for _, name := range []string{"first.txt", "second.txt"} {
+		p := filepath.Join(src, name)
+		mustWriteFile(t, p, name)
+		mustSetAgeDays(t, p, 10)
+		if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+			t.Fatalf("worker error: %v", err)
+		}
+		assertNotExists(t, p)
+	}
+
+	today := time.Now().Format(backupDateLayout)
+	assertExists(t, filepath.Join(backup, today, filepath.Base(src)+".zip"))
+	assertExists(t, filepath.Join(backup, today, filepath.Base(src)+" (2).zip"))
+
+	summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+	if err != nil || summary.Restored != 2 {
+		t.Fatalf("restore: summary=%+v err=%v", summary, err)
+	}
+	assertFileContents(t, filepath.Join(src, "first.txt"), "first.txt")
+	assertFileContents(t, filepath.Join(src, "second.txt"), "second.txt")
+}
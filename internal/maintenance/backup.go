@@ -2,6 +2,7 @@ package maintenance
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"file-maintenance/internal/logging"
+	"file-maintenance/internal/types"
 )
 
 // copyFileWithRetry copies a file from srcPath to dstPath, retrying on failure
@@ -22,26 +24,64 @@ import (
 // Behavior:
 // - Attempts the copy up to (retries + 1) total times.
 // - Uses a small backoff between attempts to avoid hammering the destination.
-// - Honors context cancellation so maintenance runs can stop cleanly.
+// - Honors context cancellation so maintenance runs can stop cleanly, also in the middle of a copy.
+// - Treats a checksum mismatch (opts.Verify) as a failed attempt, so it is retried.
+// - Resumes an interrupted plain copy from its temp file (see resumePartial).
+// - Logs the progress of files of at least copyProgressMinSize.
+// - Paces the copy with thr ([advanced] max-bandwidth / max-ops-per-second); nil does not limit.
+//
+// Returns the hex checksum of the source (opts.Hash) on success.
 //
 // Assumptions / contract:
 //   - The caller has already decided it is safe to copy this file.
 //   - The caller must ensure dstPath does not already exist (no overwrite semantics).
-//   - This function will create/overwrite a temporary file (dstPath + ".tmp") during the copy,
+//   - This function will create/overwrite a temporary file (partialPath(dstPath)) during the copy,
 //     but the final destination should not exist.
-func copyFileWithRetry(ctx context.Context, srcPath, dstPath string, retries int, log *logging.Logger) error {
+//   - A temp file left by an earlier attempt or run is continued only as far as
+//     it matches the source. It is removed when the copy succeeds or gives up
+//     after its retries, but kept when ctx stopped the copy, so a later run
+//     writing to the same dstPath continues it.
+func copyFileWithRetry(ctx context.Context, srcPath, dstPath string, retries int, opts types.BackupOptions, thr *throttle, log *logging.Logger) (string, error) {
 	var lastErr error
 
+	ctl := copyControl{
+		ctx:      ctx,
+		resume:   true,
+		throttle: thr,
+		resumed: func(offset int64) {
+			log.Infof("Resuming copy of %s after %d byte(s) already copied", srcPath, offset)
+		},
+	}
+	if info, err := os.Stat(srcPath); err == nil && info.Size() >= copyProgressMinSize {
+		size := info.Size()
+		ctl.progress = func(done int64) {
+			log.Infof("Copying %s: %d%% (%d of %d MiB)", srcPath, done*100/size, done>>20, size>>20)
+		}
+	}
+	defer func() {
+		// Nothing to resume once the copy succeeded or was given up. A copy
+		// that was only cut short by the run stopping is kept for the next.
+		if ctx.Err() == nil {
+			_ = os.Remove(partialPath(dstPath))
+		}
+	}()
+
 	for attempt := 0; attempt <= retries; attempt++ {
 		// Allow hard cancellation (max runtime reached, shutdown, etc.)
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return "", ctx.Err()
 		}
 
 		// Attempt a streaming copy (low memory usage, safe for large files).
-		if err := copyfileStream(srcPath, dstPath); err != nil {
+		sum, err := copyfileStream(srcPath, dstPath, opts, ctl)
+		if err != nil {
 			lastErr = err
 
+			// A stopped run does not retry.
+			if ctx.Err() != nil {
+				return "", fmt.Errorf("copy interrupted: %w", err)
+			}
+
 			// Backoff pattern: 250ms → 1s → 3s
 			// Keeps retries responsive without stalling the whole run.
 			backoff := backoffForAttempt(attempt)
@@ -54,7 +94,7 @@ func copyFileWithRetry(ctx context.Context, srcPath, dstPath string, retries int
 
 				select {
 				case <-ctx.Done():
-					return ctx.Err()
+					return "", ctx.Err()
 				case <-time.After(backoff):
 				}
 				continue
@@ -65,11 +105,11 @@ func copyFileWithRetry(ctx context.Context, srcPath, dstPath string, retries int
 		}
 
 		// Copy succeeded.
-		return nil
+		return sum, nil
 	}
 
 	// All attempts failed.
-	return fmt.Errorf("copy failed after %d attempts: %w", retries+1, lastErr)
+	return "", fmt.Errorf("copy failed after %d attempts: %w", retries+1, lastErr)
 }
 
 // backoffForAttempt returns the wait duration before retrying a failed copy.
@@ -98,61 +138,190 @@ func backoffForAttempt(attempt int) time.Duration {
 //
 // Implementation details:
 // - Ensures destination directory structure exists (MkdirAll).
-// - Writes into a temporary file (dstPath + ".tmp").
+// - Writes into a temporary file (partialPath(dstPath)).
 // - Closes the file handle before renaming (required on Windows).
 // - Renames temp → final path for safer "atomic-ish" behavior.
+// - Hashes the source bytes as they are read (opts.Hash) and returns the hex digest.
+// - With opts.Compress and/or opts.Key, compresses and encrypts the stream on the way out (see newStoredWriter).
+// - With opts.Verify, re-hashes the closed temp file and compares before renaming.
+// - Plain copies on Linux let the kernel clone or copy the bytes (see kernelCopy).
+// - Unless opts.Durability is none, fsyncs the temp file before the rename, and the folder and any folders created for it after it.
+// - Stops between chunks once ctl.ctx is done, and reports progress to ctl.progress.
+// - Paces every read of the copy, including hashing and verification, with ctl.throttle.
+// - With ctl.resume, keeps an interrupted plain copy's temp file and continues it on the next call.
+//
+// Verification happens before the rename so a corrupted copy never reaches dstPath.
+// Syncing the file before the rename means dstPath never names a copy whose
+// content is still only in memory; syncing the folder makes the rename itself
+// durable, so the caller may delete the source once this returns.
+// A compressed or encrypted copy is decoded to be re-hashed, so the digest
+// always describes the original content. The caller picks dstPath, including
+// its ".gz"/".enc" suffixes (see storedName).
 //
 // Safety notes:
 //   - os.Rename is not guaranteed fully atomic on all filesystems, especially network shares,
 //     but it is still much safer than writing directly to the final filename.
 //   - Rename behavior when dstPath already exists is platform/filesystem dependent.
 //     Callers should treat dstPath as "must not exist" and check before copying.
-//   - If anything fails, the temporary file is cleaned up.
-func copyfileStream(srcPath, dstPath string) error {
+//   - If anything fails (including verification), the temporary file is cleaned up.
+//   - Verification re-reads through the OS, which may serve the data from the
+//     local cache; it catches truncated or corrupted writes, not later media decay.
+func copyfileStream(srcPath, dstPath string, opts types.BackupOptions, ctl copyControl) (string, error) {
+	return streamCopy(srcPath, dstPath, storedFormat{}, opts, ctl)
+}
+
+// streamCopy is copyfileStream for a source stored in srcFormat: the source is
+// decoded as it is read, so restoring a compressed or encrypted backup with
+// plain opts writes the original file. The returned digest is of the decoded
+// content.
+//
+// When neither side is compressed or encrypted, kernelCopy is tried first. The
+// data then never reaches this process, so the source is hashed in a separate
+// read afterwards; with opts.Verify, that digest is compared with the copy's
+// exactly as for a user-space copy. The temp-file-then-rename steps are the
+// same on both paths.
+//
+// Only plain copies are resumed: a compressed or encrypted stream cannot be
+// continued from the middle, so its temp file is always started afresh.
+func streamCopy(srcPath, dstPath string, srcFormat storedFormat, opts types.BackupOptions, ctl copyControl) (string, error) {
+	h, err := newHasher(opts.Hash)
+	if err != nil {
+		return "", err
+	}
+
 	// Ensure destination directory exists (recreates relative folder structure).
-	if err := os.MkdirAll(filepath.Dir(dstPath), 0o755); err != nil {
-		return err
+	createdDirs, err := mkdirAllCreated(filepath.Dir(dstPath))
+	if err != nil {
+		return "", err
 	}
 
 	// Open source file for reading.
 	in, err := os.Open(srcPath)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer in.Close()
 
+	src, err := openStored(in, srcFormat)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
 	// Write to a temporary file first to avoid partial backups.
-	tmp := dstPath + ".tmp"
-	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	tmp := partialPath(dstPath)
+	plain := srcFormat.plain() && formatOf(opts).plain()
+	resume := ctl.resume && plain
+
+	var (
+		out    *os.File
+		offset int64
+	)
+	if resume {
+		out, offset, err = resumePartial(in, tmp, h, ctl)
+	} else {
+		out, err = os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	}
 	if err != nil {
-		return err
+		return "", err
+	}
+	if offset > 0 && ctl.resumed != nil {
+		ctl.resumed(offset)
 	}
 
-	// Ensure output is closed and temp file removed on failure.
-	closeOK := false
+	// Ensure output is closed and temp file removed unless it was renamed into
+	// place, or holds an interrupted copy to resume.
+	renamed := false
+	keepPartial := resume
 	defer func() {
 		_ = out.Close()
-		if !closeOK {
+		if !renamed && !keepPartial {
 			_ = os.Remove(tmp)
 		}
 	}()
 
-	// Streaming buffer:
-	// - 256KB balances memory usage and throughput well.
-	buf := make([]byte, 256*1024)
-	if _, err := io.CopyBuffer(out, in, buf); err != nil {
-		return err
+	meter := newCopyMeter(ctl, offset)
+
+	// Reads besides the copy itself (hashing the source after a kernel copy,
+	// verification) are paced and stopped like it, without reporting progress.
+	readCtl := copyControl{ctx: ctl.ctx, throttle: ctl.throttle}
+
+	var sum string
+	copied := false
+	if plain {
+		if copied, err = kernelCopy(out, in, meter); err != nil {
+			return "", err
+		}
+		if copied {
+			if sum, err = hashStored(srcPath, opts.Hash, storedFormat{}, readCtl); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if !copied {
+		// Streaming buffer:
+		// - 256KB balances memory usage and throughput well.
+		// The source is hashed as it is read, so it is only read once.
+		buf := make([]byte, 256*1024)
+		w, err := newStoredWriter(out, formatOf(opts), opts.CompressLevel, filepath.Base(srcPath))
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.CopyBuffer(w, io.TeeReader(meteredReader{r: src, m: meter}, h), buf); err != nil {
+			return "", err
+		}
+		// Flushes the compressor's final block and the final encrypted chunk into out.
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+	}
+
+	// The copy is complete: a failure from here on is not resumed.
+	keepPartial = false
+
+	if durable(opts) {
+		if err := syncFile(out); err != nil {
+			return "", fmt.Errorf("sync copy: %w", err)
+		}
 	}
 
-	// Close before rename (Windows requires the handle to be closed).
+	// Close before verify/rename (Windows requires the handle to be closed).
 	if err := out.Close(); err != nil {
-		return err
+		return "", err
+	}
+
+	if opts.Verify {
+		copied, err := hashCopy(tmp, opts.Hash, formatOf(opts), readCtl)
+		if err != nil {
+			return "", fmt.Errorf("verify copy: %w", err)
+		}
+		if copied != sum {
+			return "", fmt.Errorf("%w: %s source=%s copy=%s", errChecksumMismatch, srcPath, sum, copied)
+		}
 	}
-	closeOK = true
 
 	// Finalize copy by renaming temp → destination.
 	// Caller is responsible for ensuring dstPath does not exist.
-	return os.Rename(tmp, dstPath)
+	if err := os.Rename(tmp, dstPath); err != nil {
+		return "", err
+	}
+	renamed = true
+
+	if durable(opts) {
+		err := syncDir(filepath.Dir(dstPath))
+		if err == nil {
+			err = syncCreatedDirs(createdDirs)
+		}
+		if err != nil {
+			// The source must not be deleted on the strength of this copy.
+			_ = os.Remove(dstPath)
+			return "", fmt.Errorf("sync backup folder: %w", err)
+		}
+	}
+
+	return sum, nil
 }
 
 // buildBackupPath constructs the final destination path for a backup file.
@@ -1,9 +1,13 @@
 package maintenance
 
 import (
+	"context"
+	"fmt"
 	"path/filepath"
 	"testing"
 	"time"
+
+	"file-maintenance/internal/types"
 )
 
 func TestBuildBackupPath_Table(t *testing.T) {
@@ -92,3 +96,97 @@ func TestBackoffForAttempt_Table(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyfileStream_ChecksumPerAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	mustWriteFile(t, src, "backup me")
+
+	tests := []struct {
+		alg     types.HashAlgorithm
+		wantLen int // hex digest length
+	}{
+		{"", 64}, // default is SHA-256
+		{types.HashSHA256, 64},
+		{types.HashSHA512, 128},
+		{types.HashSHA1, 40},
+		{types.HashMD5, 32},
+	}
+
+	for i, tt := range tests {
+		t.Run(hashName(tt.alg), func(t *testing.T) {
+			dst := filepath.Join(dir, "out", fmt.Sprintf("dst-%d.txt", i))
+
+			sum, err := copyfileStream(src, dst, types.BackupOptions{Hash: tt.alg, Verify: true}, copyControl{})
+			if err != nil {
+				t.Fatalf("copyfileStream: %v", err)
+			}
+
+			want, err := hashFile(src, tt.alg)
+			if err != nil {
+				t.Fatalf("hashFile: %v", err)
+			}
+			if sum != want || len(sum) != tt.wantLen {
+				t.Fatalf("want %d-char checksum %s, got %s", tt.wantLen, want, sum)
+			}
+			assertExists(t, dst)
+			assertNotExists(t, partialPath(dst))
+		})
+	}
+}
+
+func TestCopyFileWithRetry_ChecksumMismatchIsRetried(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	_, log := newTestCfgAndLogger(t, root)
+
+	srcFile := filepath.Join(src, "a.txt")
+	dst := filepath.Join(backup, "a.txt")
+	mustWriteFile(t, srcFile, "payload")
+
+	// First verification reads a "corrupted" copy, the retry reads a good one.
+	calls := 0
+	orig := hashCopy
+	hashCopy = func(path string, alg types.HashAlgorithm, f storedFormat, ctl copyControl) (string, error) {
+		calls++
+		if calls == 1 {
+			return "corrupted", nil
+		}
+		return orig(path, alg, f, ctl)
+	}
+	t.Cleanup(func() { hashCopy = orig })
+
+	opts := types.BackupOptions{Hash: types.HashSHA256, Verify: true}
+	if _, err := copyFileWithRetry(context.Background(), srcFile, dst, 1, opts, nil, log); err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 verification passes, got %d", calls)
+	}
+	assertExists(t, dst)
+}
+
+func TestWorker_Integration_VerifyMismatchKeepsSource(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Retries = 0
+	cfg.Backup = types.BackupOptions{Hash: types.HashSHA256, Verify: true}
+
+	p := filepath.Join(src, "old.txt")
+	mustWriteFile(t, p, "payload")
+	mustSetAgeDays(t, p, 10)
+
+	orig := hashCopy
+	hashCopy = func(string, types.HashAlgorithm, storedFormat, copyControl) (string, error) { return "corrupted", nil }
+	t.Cleanup(func() { hashCopy = orig })
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	assertExists(t, p)
+	if n := countBackupsWithBase(t, backup, "old.txt"); n != 0 {
+		t.Fatalf("expected no backup copy after verification failure, got %d", n)
+	}
+}
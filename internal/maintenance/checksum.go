@@ -0,0 +1,57 @@
+package maintenance
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+
+	"file-maintenance/internal/types"
+)
+
+// errChecksumMismatch is returned when a verified copy does not match its
+// source. It is an ordinary copy failure, so copyFileWithRetry retries it.
+var errChecksumMismatch = errors.New("backup checksum mismatch")
+
+// hashCopy computes the checksum of a finished copy during verification,
+// decoding it first when needed (see hashStored). Tests replace it to
+// simulate a copy corrupted in transit.
+var hashCopy = hashStored
+
+// newHasher returns a fresh hash for alg. An empty alg means SHA-256.
+//
+// SHA-1 and MD5 are offered for speed on slow CPUs and for matching checksums
+// produced by other tools; they detect corruption just as well, which is all
+// verification needs.
+func newHasher(alg types.HashAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case "", types.HashSHA256:
+		return sha256.New(), nil
+	case types.HashSHA512:
+		return sha512.New(), nil
+	case types.HashSHA1:
+		return sha1.New(), nil
+	case types.HashMD5:
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", alg)
+	}
+}
+
+// hashFile streams path through a new alg hash and returns the hex digest.
+// Reads that belong to a run go through hashStored instead, with the run's
+// copyControl.
+func hashFile(path string, alg types.HashAlgorithm) (string, error) {
+	return hashStored(path, alg, storedFormat{}, copyControl{})
+}
+
+// hashName returns the display name of alg, resolving the empty default.
+func hashName(alg types.HashAlgorithm) string {
+	if alg == "" {
+		return string(types.HashSHA256)
+	}
+	return string(alg)
+}
@@ -0,0 +1,127 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"file-maintenance/internal/types"
+)
+
+// errBackupCollision is returned when the backup destination holds a different
+// file and the collision policy does not allow writing a versioned name.
+var errBackupCollision = errors.New("backup destination holds a different file")
+
+// maxBackupVersions caps how many "name (n).ext" candidates are tried before
+// giving up, so a pathological destination cannot stall a run.
+const maxBackupVersions = 1000
+
+// backupTarget is where resolveBackupTarget decided a backup belongs.
+type backupTarget struct {
+	// path is the backup file to write, or the existing identical backup.
+	path string
+
+	// reused is true when path already holds identical content and no copy
+	// is needed.
+	reused bool
+
+	// sum is the source checksum when it was computed while comparing
+	// (always set when reused is true).
+	sum string
+}
+
+// resolveBackupTarget decides where the backup of srcPath is written when
+// dstPath may already exist, for example after a second run on the same day.
+//
+// Outcomes:
+//   - dstPath is free: copy to dstPath as usual.
+//   - dstPath or an earlier version already holds identical content (same size
+//     and checksum): reused is set, no copy is needed and the source may be deleted.
+//   - CollisionVersion and the name is taken by a different file: copy to the
+//     first free "name (n).ext".
+//   - CollisionKeep and the name is taken by a different file: errBackupCollision;
+//     nothing is copied and the source must be kept.
+//
+// Identity is decided by size first and only then by checksum, so differing
+// files are usually rejected without reading them.
+//
+// With opts.Compress or opts.Key every candidate carries the stored suffixes
+// ("name (n).ext.gz.enc"), and existing backups are compared by the checksum
+// of their decoded content, since their stored size says nothing about it.
+//
+// Files are hashed through ctl (see hashStored).
+func resolveBackupTarget(srcPath, dstPath string, opts types.BackupOptions, ctl copyControl) (backupTarget, error) {
+	format := formatOf(opts)
+	if stored := storedName(dstPath, format); !DoesFileExist(stored) {
+		return backupTarget{path: stored}, nil
+	}
+
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return backupTarget{}, err
+	}
+
+	// Hashed lazily: only needed if some existing backup has the same size.
+	var srcSum string
+	sameContent := func(existing string) (bool, error) {
+		info, err := os.Stat(existing)
+		if err != nil || !info.Mode().IsRegular() {
+			// Unreadable or not a plain file: never treat as our backup.
+			return false, nil
+		}
+		if format.plain() && info.Size() != srcInfo.Size() {
+			return false, nil
+		}
+		if srcSum == "" {
+			if srcSum, err = hashStored(srcPath, opts.Hash, storedFormat{}, ctl); err != nil {
+				return false, err
+			}
+		}
+		existingSum, err := hashStored(existing, opts.Hash, format, ctl)
+		if err != nil {
+			return false, nil
+		}
+		return existingSum == srcSum, nil
+	}
+
+	for n := 1; n <= maxBackupVersions; n++ {
+		candidate := dstPath
+		if n > 1 {
+			candidate = versionedPath(dstPath, n)
+		}
+		candidate = storedName(candidate, format)
+
+		if !DoesFileExist(candidate) {
+			return backupTarget{path: candidate, sum: srcSum}, nil
+		}
+
+		same, err := sameContent(candidate)
+		if err != nil {
+			return backupTarget{}, err
+		}
+		if same {
+			return backupTarget{path: candidate, reused: true, sum: srcSum}, nil
+		}
+
+		if opts.Collision == types.CollisionKeep {
+			return backupTarget{}, fmt.Errorf("%w: %s", errBackupCollision, dstPath)
+		}
+	}
+
+	return backupTarget{}, fmt.Errorf("%w: no free name after %d versions: %s", errBackupCollision, maxBackupVersions, dstPath)
+}
+
+// versionedPath returns dstPath with " (n)" inserted before the extension,
+// e.g. "report.pdf" -> "report (2).pdf", matching Windows Explorer's style.
+func versionedPath(dstPath string, n int) string {
+	dir, base := filepath.Split(dstPath)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if stem == "" {
+		// Dotfiles such as ".env" have no stem; version the whole name.
+		stem, ext = base, ""
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, n, ext))
+}
@@ -0,0 +1,154 @@
+package maintenance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestVersionedPath_Table(t *testing.T) {
+	dir := filepath.Join("backup", "30Jan26", "scans")
+
+	tests := []struct {
+		name string
+		base string
+		n    int
+		want string
+	}{
+		{name: "with extension", base: "report.pdf", n: 2, want: "report (2).pdf"},
+		{name: "double extension keeps last", base: "logs.tar.gz", n: 3, want: "logs.tar (3).gz"},
+		{name: "no extension", base: "README", n: 2, want: "README (2)"},
+		{name: "dotfile", base: ".env", n: 2, want: ".env (2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := versionedPath(filepath.Join(dir, tt.base), tt.n)
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Fatalf("want %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestResolveBackupTarget_Table(t *testing.T) {
+	tests := []struct {
+		name          string
+		existing      map[string]string // backup file name -> contents
+		collision     types.CollisionPolicy
+		wantName      string
+		wantBackedUp  bool
+		wantCollision bool
+	}{
+		{name: "free name", wantName: "a.txt"},
+		{name: "identical content", existing: map[string]string{"a.txt": "payload"}, wantName: "a.txt", wantBackedUp: true},
+		{name: "same size different content", existing: map[string]string{"a.txt": "PAYLOAD"}, wantName: "a (2).txt"},
+		{name: "different size", existing: map[string]string{"a.txt": "other"}, wantName: "a (2).txt"},
+		{
+			name:         "identical earlier version",
+			existing:     map[string]string{"a.txt": "other", "a (2).txt": "payload"},
+			wantName:     "a (2).txt",
+			wantBackedUp: true,
+		},
+		{
+			name:     "skips taken versions",
+			existing: map[string]string{"a.txt": "other", "a (2).txt": "older"},
+			wantName: "a (3).txt",
+		},
+		{name: "keep refuses different content", existing: map[string]string{"a.txt": "other"}, collision: types.CollisionKeep, wantCollision: true},
+		{name: "keep accepts identical content", existing: map[string]string{"a.txt": "payload"}, collision: types.CollisionKeep, wantName: "a.txt", wantBackedUp: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src", "a.txt")
+			mustMkdirAll(t, filepath.Dir(src))
+			mustWriteFile(t, src, "payload")
+
+			backupDir := filepath.Join(dir, "backup")
+			mustMkdirAll(t, backupDir)
+			for name, contents := range tt.existing {
+				mustWriteFile(t, filepath.Join(backupDir, name), contents)
+			}
+
+			opts := types.BackupOptions{Hash: types.HashSHA256, Collision: tt.collision}
+			got, err := resolveBackupTarget(src, filepath.Join(backupDir, "a.txt"), opts, copyControl{})
+
+			if tt.wantCollision {
+				if !errors.Is(err, errBackupCollision) {
+					t.Fatalf("expected errBackupCollision, got target=%+v err=%v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := filepath.Join(backupDir, tt.wantName); got.path != want || got.reused != tt.wantBackedUp {
+				t.Fatalf("want (%q, reused=%v), got (%q, reused=%v)", want, tt.wantBackedUp, got.path, got.reused)
+			}
+			if got.reused && got.sum == "" {
+				t.Fatalf("expected source checksum for reused backup")
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_SameDayRunsVersionCollidingBackups(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+
+	p := filepath.Join(src, "scan.pdf")
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+
+	// Two runs on the same day each find a different old file with the same name.
+	for _, contents := range []string{"first scan", "second scan"} {
+		mustWriteFile(t, p, contents)
+		mustSetAgeDays(t, p, 10)
+
+		if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+			t.Fatalf("worker error: %v", err)
+		}
+		assertNotExists(t, p)
+	}
+
+	dateDir := filepath.Join(backup, time.Now().Format("02Jan06"), filepath.Base(src))
+	for name, want := range map[string]string{"scan.pdf": "first scan", "scan (2).pdf": "second scan"} {
+		b, err := os.ReadFile(filepath.Join(dateDir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(b) != want {
+			t.Fatalf("%s: want %q, got %q", name, want, b)
+		}
+	}
+}
+
+func TestWorker_Integration_CollisionKeepRetainsSource(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.BackupOptions{Hash: types.HashSHA256, Collision: types.CollisionKeep}
+
+	p := filepath.Join(src, "scan.pdf")
+	mustWriteFile(t, p, "new contents")
+	mustSetAgeDays(t, p, 10)
+
+	existing := filepath.Join(backup, time.Now().Format("02Jan06"), filepath.Base(src), "scan.pdf")
+	mustMkdirAll(t, filepath.Dir(existing))
+	mustWriteFile(t, existing, "older contents")
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	assertExists(t, p)
+	assertNotExists(t, versionedPath(existing, 2))
+}
@@ -0,0 +1,70 @@
+package maintenance
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+
+	"file-maintenance/internal/types"
+)
+
+// gzipSuffix is appended to the name of backups written with compress=gzip.
+const gzipSuffix = ".gz"
+
+// isCompressed reports whether c stores files in a compressed form.
+func isCompressed(c types.Compression) bool {
+	return c == types.CompressGzip
+}
+
+// nopWriteCloser lets uncompressed output share the compressed code path.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressWriter wraps w so that everything written to it is stored with
+// compression c. level is the gzip level (1-9); 0 means gzip's default.
+// name is recorded in the gzip header as the original file name.
+//
+// Close must be called before the underlying file is closed, or the gzip
+// trailer (and with it the end of the data) is lost.
+func newCompressWriter(w io.Writer, c types.Compression, level int, name string) (io.WriteCloser, error) {
+	if !isCompressed(c) {
+		return nopWriteCloser{w}, nil
+	}
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	zw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+	zw.Name = name
+	return zw, nil
+}
+
+// newDecompressReader wraps r so that reading from it yields the original
+// content of a file stored with compression c.
+func newDecompressReader(r io.Reader, c types.Compression) (io.ReadCloser, error) {
+	if !isCompressed(c) {
+		return io.NopCloser(r), nil
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open gzip stream: %w", err)
+	}
+	return zr, nil
+}
+
+// estimatedBackupBytes is the space a backup of a sizeBytes file is expected
+// to take for the batch space check.
+//
+// Compressed backups are counted at sizeBytes / ratio when [backup]
+// compress-ratio is set (rounded up), and at their raw size otherwise, so the
+// check never assumes savings nobody configured.
+func estimatedBackupBytes(sizeBytes uint64, c types.Compression, ratio float64) uint64 {
+	if !isCompressed(c) || ratio <= 1 {
+		return sizeBytes
+	}
+	return uint64(math.Ceil(float64(sizeBytes) / ratio))
+}
@@ -0,0 +1,218 @@
+package maintenance
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestEstimatedBackupBytes_Table(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     uint64
+		compress types.Compression
+		ratio    float64
+		want     uint64
+	}{
+		{name: "uncompressed ignores ratio", size: 1000, compress: types.CompressNone, ratio: 10, want: 1000},
+		{name: "compressed without ratio is raw size", size: 1000, compress: types.CompressGzip, want: 1000},
+		{name: "compressed with ratio", size: 1000, compress: types.CompressGzip, ratio: 10, want: 100},
+		{name: "rounds up", size: 1001, compress: types.CompressGzip, ratio: 10, want: 101},
+		{name: "ratio of 1 is raw size", size: 1000, compress: types.CompressGzip, ratio: 1, want: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimatedBackupBytes(tt.size, tt.compress, tt.ratio); got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// readGzipFile returns the decompressed contents of a gzip file.
+func readGzipFile(t *testing.T, p string) string {
+	t.Helper()
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatalf("open %q: %v", p, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("%q is not a gzip file: %v", p, err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read gzip %q: %v", p, err)
+	}
+	return string(b)
+}
+
+func TestCopyfileStream_GzipRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "export.csv")
+	payload := strings.Repeat("id,name,amount\n1,widget,9.99\n", 1000)
+	mustWriteFile(t, src, payload)
+
+	opts := types.BackupOptions{Hash: types.HashSHA256, Verify: true, Compress: types.CompressGzip, CompressLevel: 9}
+	stored := filepath.Join(dir, "backup", "export.csv.gz")
+	sum, err := copyfileStream(src, stored, opts, copyControl{})
+	if err != nil {
+		t.Fatalf("compressed copy: %v", err)
+	}
+
+	// The digest describes the original content, not the gzip stream.
+	want, err := hashFile(src, types.HashSHA256)
+	if err != nil {
+		t.Fatalf("hash source: %v", err)
+	}
+	if sum != want {
+		t.Fatalf("want source checksum %s, got %s", want, sum)
+	}
+	if got, _ := hashStored(stored, types.HashSHA256, storedFormat{compress: types.CompressGzip}, copyControl{}); got != want {
+		t.Fatalf("want stored checksum %s, got %s", want, got)
+	}
+
+	if got := readGzipFile(t, stored); got != payload {
+		t.Fatalf("decompressed backup does not match source")
+	}
+	info, err := os.Stat(stored)
+	if err != nil {
+		t.Fatalf("stat backup: %v", err)
+	}
+	if info.Size() >= int64(len(payload)) {
+		t.Fatalf("expected compressed backup smaller than %d bytes, got %d", len(payload), info.Size())
+	}
+
+	// Reading it back through streamCopy yields the original file.
+	restored := filepath.Join(dir, "restored", "export.csv")
+	if _, err := streamCopy(stored, restored, storedFormat{compress: types.CompressGzip}, types.BackupOptions{Hash: types.HashSHA256, Verify: true}, copyControl{}); err != nil {
+		t.Fatalf("decompressing copy: %v", err)
+	}
+	assertFileContents(t, restored, payload)
+}
+
+func TestWorker_Integration_CompressedBackupAndRestore(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Compress = types.CompressGzip
+
+	plain := filepath.Join(root, "plain")
+	mustMkdirAll(t, plain)
+
+	logFile := filepath.Join(src, "app.log")
+	csvFile := filepath.Join(plain, "export.csv")
+	for _, p := range []string{logFile, csvFile} {
+		mustWriteFile(t, p, "contents of "+filepath.Base(p))
+		mustSetAgeDays(t, p, 10)
+	}
+
+	pathconfig := []types.PathConfig{
+		{Path: src, Backup: true, IsDir: true},
+		{Path: plain, Backup: true, IsDir: true, Compress: types.CompressNone},
+	}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	assertNotExists(t, logFile)
+	assertNotExists(t, csvFile)
+
+	today := time.Now().Format(backupDateLayout)
+	gz := filepath.Join(backup, today, filepath.Base(src), "app.log.gz")
+	if got := readGzipFile(t, gz); got != "contents of app.log" {
+		t.Fatalf("unexpected compressed backup contents %q", got)
+	}
+	assertNotExists(t, filepath.Join(backup, today, filepath.Base(src), "app.log"))
+	// The per-path compress=none wins over [backup] compress=gzip.
+	assertFileContents(t, filepath.Join(backup, today, "plain", "export.csv"), "contents of export.csv")
+
+	records, _, err := ReadBackupManifest(filepath.Join(backup, today, backupManifestName))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	for _, rec := range records {
+		want := ""
+		if rec.SourcePath == logFile {
+			want = "gzip"
+		}
+		if rec.Compression != want {
+			t.Fatalf("%s: want compression %q, got %q", rec.SourcePath, want, rec.Compression)
+		}
+	}
+
+	// Backing up the same content again reuses the compressed copy.
+	mustWriteFile(t, logFile, "contents of app.log")
+	mustSetAgeDays(t, logFile, 10)
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("second worker run: %v", err)
+	}
+	assertNotExists(t, logFile)
+	assertNotExists(t, filepath.Join(backup, today, filepath.Base(src), "app (2).log.gz"))
+
+	// Restore decompresses back to the original name, also into a target.
+	if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	assertFileContents(t, logFile, "contents of app.log")
+	assertFileContents(t, csvFile, "contents of export.csv")
+
+	target := filepath.Join(root, "restored")
+	if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today, Target: target}, log); err != nil {
+		t.Fatalf("restore to target: %v", err)
+	}
+	assertFileContents(t, filepath.Join(target, filepath.Base(src), "app.log"), "contents of app.log")
+}
+
+func TestWorker_Integration_CompressRatioSpaceEstimate(t *testing.T) {
+	payload := strings.Repeat("a", 1000)
+
+	tests := []struct {
+		name     string
+		ratio    float64
+		wantCopy bool
+	}{
+		{name: "raw size does not fit", ratio: 0, wantCopy: false},
+		{name: "estimated size fits", ratio: 10, wantCopy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, src, backup := newSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.Retries = 0
+			cfg.Backup = types.BackupOptions{Compress: types.CompressGzip, CompressRatio: tt.ratio}
+
+			target := filepath.Join(src, "a.log")
+			mustWriteFile(t, target, payload)
+			mustSetAgeDays(t, target, 10)
+
+			pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+			err := Worker(pathconfig, backup, cfg, log, fakeDiskSpaceChecker{availableBytes: 500})
+
+			if !tt.wantCopy {
+				if err == nil {
+					t.Fatalf("expected insufficient space error, got nil")
+				}
+				assertExists(t, target)
+				return
+			}
+			if err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+			assertNotExists(t, target)
+			if countBackupsWithBase(t, backup, "a.log.gz") != 1 {
+				t.Fatalf("expected one compressed backup")
+			}
+		})
+	}
+}
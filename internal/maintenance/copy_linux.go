@@ -0,0 +1,96 @@
+//go:build linux
+
+package maintenance
+
+import (
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyFileRangeChunk is how much one copy_file_range call is asked to copy.
+// The copy can only stop between calls, so it is kept small enough for a slow
+// share to finish one in seconds.
+const copyFileRangeChunk = 64 << 20
+
+// kernelCopy copies the rest of src, from its current offset, to dst at its
+// current offset, without passing the data through user space:
+//   - First as a reflink clone (FICLONE), which on btrfs, XFS and other
+//     copy-on-write filesystems shares the source's extents: it is instant and
+//     uses no extra space until one of the files changes.
+//   - Otherwise with copy_file_range(2), which copies inside the kernel and
+//     lets filesystems and NFS/SMB servers offload the copy.
+//
+// It returns false, with nothing written, when neither works for this pair
+// of files (different filesystems on older kernels, unsupported filesystems),
+// and the caller copies in user space instead. copy_file_range also reports
+// the end of the file by copying nothing, which some filesystems (procfs,
+// FUSE, some network filesystems) do without having copied anything, so the
+// copy only counts when it copied exactly the rest of src by its size;
+// otherwise both files are put back where they were and false is returned
+// too. An error after part of the file was copied is returned as is, so the
+// copy fails and is retried. m is
+// checked and advanced between copy_file_range calls, which are made smaller
+// under a bandwidth limit (see throttle.chunk).
+//
+// A clone always covers the whole file, which is also correct for a copy
+// resumed at an offset.
+func kernelCopy(dst, src *os.File, m *copyMeter) (bool, error) {
+	if err := unix.IoctlFileClone(int(dst.Fd()), int(src.Fd())); err == nil {
+		return true, nil
+	}
+
+	info, err := src.Stat()
+	if err != nil {
+		return false, nil
+	}
+	srcStart, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, nil
+	}
+	dstStart, err := dst.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, nil
+	}
+	remaining := info.Size() - srcStart
+
+	copied := 0
+	for {
+		if err := m.check(); err != nil {
+			return false, err
+		}
+		n, err := unix.CopyFileRange(int(src.Fd()), nil, int(dst.Fd()), nil, m.chunk(copyFileRangeChunk), 0)
+		if err != nil {
+			if copied == 0 {
+				return false, nil
+			}
+			return false, &os.PathError{Op: "copy_file_range", Path: dst.Name(), Err: err}
+		}
+		if n == 0 {
+			if copied > 0 && int64(copied) == remaining {
+				return true, nil
+			}
+			return false, undoKernelCopy(dst, src, dstStart, srcStart, m, copied)
+		}
+		copied += n
+		if err := m.add(int64(n)); err != nil {
+			return false, err
+		}
+	}
+}
+
+// undoKernelCopy puts dst and src back at their offsets from before a
+// kernelCopy that copied n bytes but is not trusted, and takes those bytes
+// back off m, so the user-space copy starts over from the same place.
+func undoKernelCopy(dst, src *os.File, dstStart, srcStart int64, m *copyMeter, n int) error {
+	m.done -= int64(n)
+	if err := dst.Truncate(dstStart); err != nil {
+		return err
+	}
+	if _, err := dst.Seek(dstStart, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := src.Seek(srcStart, io.SeekStart)
+	return err
+}
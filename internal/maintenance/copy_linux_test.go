@@ -0,0 +1,101 @@
+//go:build linux
+
+package maintenance
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"file-maintenance/internal/types"
+)
+
+func TestKernelCopy_Sizes(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, size := range []int{0, 1, 3*1024*1024 + 7} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 31)
+		}
+		srcPath := filepath.Join(dir, "src.bin")
+		dstPath := filepath.Join(dir, "dst.bin")
+		if err := os.WriteFile(srcPath, data, 0o644); err != nil {
+			t.Fatalf("write source: %v", err)
+		}
+
+		src, err := os.Open(srcPath)
+		if err != nil {
+			t.Fatalf("open source: %v", err)
+		}
+		dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("create destination: %v", err)
+		}
+		copied, err := kernelCopy(dst, src, newCopyMeter(copyControl{}, 0))
+		_ = src.Close()
+		_ = dst.Close()
+		if err != nil {
+			t.Fatalf("size %d: kernelCopy: %v", size, err)
+		}
+		if size == 0 {
+			// Nothing copied is never taken for a complete copy.
+			if copied {
+				t.Fatalf("size 0: kernelCopy reported a copy")
+			}
+			continue
+		}
+		if !copied {
+			t.Skip("no kernel-level copy on this filesystem")
+		}
+
+		got, err := os.ReadFile(dstPath)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("size %d: copy differs from source (err=%v)", size, err)
+		}
+	}
+}
+
+func TestKernelCopy_FallsBackWhenNothingIsCopied(t *testing.T) {
+	// procfs files report a size of 0 and, depending on the kernel,
+	// copy_file_range fails or copies nothing from them, although they have
+	// content.
+	src, err := os.Open("/proc/self/status")
+	if err != nil {
+		t.Skipf("no procfs: %v", err)
+	}
+	defer src.Close()
+	dstPath := filepath.Join(t.TempDir(), "status")
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("create destination: %v", err)
+	}
+	defer dst.Close()
+
+	copied, err := kernelCopy(dst, src, newCopyMeter(copyControl{}, 0))
+	if err != nil {
+		t.Fatalf("kernelCopy: %v", err)
+	}
+	if copied {
+		t.Fatalf("kernelCopy reported a copy of a procfs file")
+	}
+	if off, _ := src.Seek(0, io.SeekCurrent); off != 0 {
+		t.Fatalf("source left at offset %d", off)
+	}
+}
+
+func TestCopyfileStream_CopiesWhatKernelCopyCannot(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "status")
+	if _, err := copyfileStream("/proc/self/status", dst, types.BackupOptions{Verify: true}, copyControl{}); err != nil {
+		t.Skipf("copy from procfs: %v", err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("read copy: %v", err)
+	}
+	if !bytes.Contains(got, []byte("Name:")) {
+		t.Fatalf("copy of /proc/self/status is missing its content: %q", got)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package maintenance
+
+import "os"
+
+// kernelCopy has no kernel-level copy to offer on this platform; the caller
+// copies in user space.
+func kernelCopy(dst, src *os.File, m *copyMeter) (bool, error) {
+	return false, nil
+}
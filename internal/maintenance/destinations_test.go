@@ -0,0 +1,296 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestBackupDestinations_Table(t *testing.T) {
+	tests := []struct {
+		name  string
+		root  string
+		extra []string
+		want  []string
+	}{
+		{name: "root only", root: "a", want: []string{"a"}},
+		{name: "root listed first", root: "a", extra: []string{"a", "b"}, want: []string{"a", "b"}},
+		{name: "root not listed", root: "a", extra: []string{"b", "c"}, want: []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backupDestinations(tt.root, tt.extra); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRequiredCopies_Table(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  types.BackupOptions
+		dests int
+		want  int
+	}{
+		{name: "failover", opts: types.BackupOptions{Mode: types.BackupModeFailover}, dests: 3, want: 1},
+		{name: "default mode", dests: 3, want: 1},
+		{name: "mirror all", opts: types.BackupOptions{Mode: types.BackupModeMirror}, dests: 3, want: 3},
+		{name: "mirror quorum", opts: types.BackupOptions{Mode: types.BackupModeMirror, Quorum: 2}, dests: 3, want: 2},
+		{name: "mirror single destination", opts: types.BackupOptions{Mode: types.BackupModeMirror, Quorum: 2}, dests: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.RequiredCopies(tt.dests); got != tt.want {
+				t.Fatalf("want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// newDestinationSandbox adds a second and third backup root next to backup.
+func newDestinationSandbox(t *testing.T) (root, src string, backups []string) {
+	t.Helper()
+	root, src, backup := newSandbox(t)
+	backups = []string{backup, filepath.Join(root, "backup2"), filepath.Join(root, "backup3")}
+	for _, b := range backups[1:] {
+		mustMkdirAll(t, b)
+	}
+	return root, src, backups
+}
+
+func backupCopyPath(backupRoot, src, name string) string {
+	return filepath.Join(backupRoot, time.Now().Format(backupDateLayout), filepath.Base(src), name)
+}
+
+func TestWorker_Integration_FailoverDestinations(t *testing.T) {
+	tests := []struct {
+		name    string
+		disk    perRootDisk
+		broken  int // index of a destination made unreachable, or -1
+		wantIn  int // index of the destination that should hold the copy, or -1
+		wantErr bool
+	}{
+		{name: "primary used while usable", broken: -1, wantIn: 0},
+		{name: "primary full", disk: perRootDisk{"backup": 1}, broken: -1, wantIn: 1},
+		{name: "primary unreachable", broken: 0, wantIn: 1},
+		{name: "all unusable", disk: perRootDisk{"backup": 1, "backup2": 1, "backup3": 1}, broken: -1, wantIn: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, src, backups := newDestinationSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.Retries = 0
+			cfg.Backup = types.DefaultBackupOptions()
+			cfg.BackupDirs = backups
+
+			disk := perRootDisk{}
+			for _, b := range backups {
+				disk[b] = 1 << 30
+				if n, ok := tt.disk[filepath.Base(b)]; ok {
+					disk[b] = n
+				}
+			}
+			if tt.broken >= 0 {
+				// A root that is a file fails CheckBackupPath.
+				backups[tt.broken] = filepath.Join(root, "missing-share")
+				mustWriteFile(t, backups[tt.broken], "not a directory")
+			}
+
+			p := filepath.Join(src, "a.txt")
+			mustWriteFile(t, p, "payload")
+			mustSetAgeDays(t, p, 10)
+
+			pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+			err := Worker(pathconfig, backups[0], cfg, log, disk)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error when no destination is usable")
+				}
+				assertExists(t, p)
+				return
+			}
+			if err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+			assertNotExists(t, p)
+			for i, b := range backups {
+				if i == tt.broken {
+					continue
+				}
+				copyPath := backupCopyPath(b, src, "a.txt")
+				if i == tt.wantIn {
+					assertFileContents(t, copyPath, "payload")
+					assertExists(t, filepath.Join(b, time.Now().Format(backupDateLayout), backupManifestName))
+				} else {
+					assertNotExists(t, copyPath)
+				}
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_MirrorDestinations(t *testing.T) {
+	tests := []struct {
+		name       string
+		quorum     int
+		breakCopy  bool // one destination accepts the space check but every copy fails
+		unusable   bool // one destination fails the space check
+		wantDelete bool
+		wantErr    bool
+	}{
+		{name: "all copies", wantDelete: true},
+		{name: "copy failure keeps source", breakCopy: true},
+		{name: "copy failure within quorum", quorum: 2, breakCopy: true, wantDelete: true},
+		{name: "unusable destination within quorum", quorum: 2, unusable: true, wantDelete: true},
+		{name: "unusable destination below quorum", unusable: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, src, backups := newDestinationSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.Retries = 0
+			cfg.Backup = types.DefaultBackupOptions()
+			cfg.Backup.Mode = types.BackupModeMirror
+			cfg.Backup.Quorum = tt.quorum
+			cfg.BackupDirs = backups
+
+			disk := perRootDisk{}
+			for _, b := range backups {
+				disk[b] = 1 << 30
+			}
+			last := backups[len(backups)-1]
+			if tt.unusable {
+				disk[last] = 1
+			}
+			if tt.breakCopy {
+				// The dated folder is a file, so the copy cannot create its directory.
+				mustWriteFile(t, filepath.Join(last, time.Now().Format(backupDateLayout)), "in the way")
+			}
+
+			p := filepath.Join(src, "a.txt")
+			mustWriteFile(t, p, "payload")
+			mustSetAgeDays(t, p, 10)
+
+			pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+			err := Worker(pathconfig, backups[0], cfg, log, disk)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error when fewer destinations than the quorum are usable")
+				}
+				assertExists(t, p)
+				return
+			}
+			if err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+
+			if tt.wantDelete {
+				assertNotExists(t, p)
+			} else {
+				assertExists(t, p)
+			}
+			for _, b := range backups[:len(backups)-1] {
+				assertFileContents(t, backupCopyPath(b, src, "a.txt"), "payload")
+			}
+			if tt.unusable {
+				assertNotExists(t, backupCopyPath(last, src, "a.txt"))
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_PerPathDest(t *testing.T) {
+	tests := []struct {
+		name       string
+		archiveCap uint64
+		wantErr    bool
+	}{
+		{name: "each path lands on its own root", archiveCap: 1 << 30},
+		// No fallback to [backup] path: the run stops before the batch is touched.
+		{name: "per-path dest without space", archiveCap: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, src, backup := newSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.Retries = 0
+			cfg.Backup = types.DefaultBackupOptions()
+
+			scans := filepath.Join(root, "scans")
+			archive := filepath.Join(root, "scan-archive")
+			mustMkdirAll(t, scans)
+			mustMkdirAll(t, archive)
+
+			general := filepath.Join(src, "general.txt")
+			scan := filepath.Join(scans, "scan.pdf")
+			for _, p := range []string{general, scan} {
+				mustWriteFile(t, p, "payload")
+				mustSetAgeDays(t, p, 10)
+			}
+
+			disk := &recordingDisk{available: perRootDisk{backup: 1 << 30, archive: tt.archiveCap}}
+			pathconfig := []types.PathConfig{
+				{Path: src, Backup: true, IsDir: true},
+				{Path: scans, Backup: true, IsDir: true, Dest: archive},
+			}
+			err := Worker(pathconfig, backup, cfg, log, disk)
+
+			// Both roots are space-checked for the same batch.
+			if !disk.checked[backup] || !disk.checked[archive] {
+				t.Fatalf("expected a space check per destination, got %v", disk.checked)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error when the per-path dest has no space")
+				}
+				assertExists(t, general)
+				assertExists(t, scan)
+				assertNotExists(t, backupCopyPath(backup, scans, "scan.pdf"))
+				return
+			}
+			if err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+
+			assertNotExists(t, general)
+			assertNotExists(t, scan)
+			assertFileContents(t, backupCopyPath(backup, src, "general.txt"), "payload")
+			assertFileContents(t, backupCopyPath(archive, scans, "scan.pdf"), "payload")
+			assertNotExists(t, backupCopyPath(backup, scans, "scan.pdf"))
+			assertNotExists(t, backupCopyPath(archive, src, "general.txt"))
+		})
+	}
+}
+
+// recordingDisk wraps perRootDisk and remembers which roots were checked.
+type recordingDisk struct {
+	available perRootDisk
+	checked   map[string]bool
+}
+
+func (d *recordingDisk) AvailableBytes(path string) (uint64, error) {
+	if d.checked == nil {
+		d.checked = make(map[string]bool)
+	}
+	d.checked[path] = true
+	return d.available.AvailableBytes(path)
+}
+
+func (d *recordingDisk) TotalBytes(path string) (uint64, error) {
+	return d.available.TotalBytes(path)
+}
@@ -0,0 +1,35 @@
+//go:build linux || darwin
+
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// sameDevice reports whether a and b are on the same filesystem, so that a
+// file can be renamed from one to the other.
+func sameDevice(a, b string) (bool, error) {
+	devA, err := deviceOf(a)
+	if err != nil {
+		return false, err
+	}
+	devB, err := deviceOf(b)
+	if err != nil {
+		return false, err
+	}
+	return devA == devB, nil
+}
+
+func deviceOf(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("no stat data for %s", path)
+	}
+	return uint64(st.Dev), nil
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package maintenance
+
+import (
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// sameDevice reports whether a and b are on the same volume, so that a file
+// can be renamed from one to the other. Volumes are compared by their mount
+// point ("C:\", a mounted folder, or "\\server\share\" for UNC paths).
+func sameDevice(a, b string) (bool, error) {
+	volA, err := volumeOf(a)
+	if err != nil {
+		return false, err
+	}
+	volB, err := volumeOf(b)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(volA, volB), nil
+}
+
+func volumeOf(path string) (string, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	if err := windows.GetVolumePathName(p, &buf[0], uint32(len(buf))); err != nil {
+		return "", err
+	}
+	return windows.UTF16ToString(buf), nil
+}
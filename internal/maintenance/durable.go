@@ -0,0 +1,74 @@
+package maintenance
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// fsyncTime accumulates the time spent flushing backups to disk
+// ([backup] durability=fsync). The worker reports the difference per batch;
+// only its processor goroutine copies files during a run.
+var fsyncTime atomic.Int64
+
+// durable reports whether backups written with opts are flushed to disk.
+func durable(opts types.BackupOptions) bool {
+	return opts.Durability != types.DurabilityNone
+}
+
+// syncFile flushes f's content and metadata to stable storage.
+func syncFile(f *os.File) error {
+	start := time.Now()
+	err := f.Sync()
+	fsyncTime.Add(int64(time.Since(start)))
+	return err
+}
+
+// syncDir flushes the entries of dir, so that a file just created in it or
+// renamed into it survives a power loss (see syncDirectory).
+func syncDir(dir string) error {
+	start := time.Now()
+	err := syncDirectory(dir)
+	fsyncTime.Add(int64(time.Since(start)))
+	return err
+}
+
+// mkdirAllCreated is os.MkdirAll(dir, 0o755) that also returns the folders it
+// had to create, outermost first, for syncCreatedDirs.
+func mkdirAllCreated(dir string) ([]string, error) {
+	var created []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		created = append(created, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	slices.Reverse(created)
+	return created, nil
+}
+
+// syncCreatedDirs syncs the parent of each folder in created (see
+// mkdirAllCreated), so that new folders such as a run's <DDMmmYY> and
+// <folder-name> exist after a power loss, up to the folder that was already
+// there. Syncing only the innermost folder would leave a backup renamed into
+// it unreachable if one of its new parents were lost.
+func syncCreatedDirs(created []string) error {
+	for _, d := range created {
+		if err := syncDir(filepath.Dir(d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
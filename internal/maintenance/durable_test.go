@@ -0,0 +1,81 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"file-maintenance/internal/types"
+)
+
+func TestCopyfileStream_Durability(t *testing.T) {
+	tests := []struct {
+		name       string
+		durability types.Durability
+		wantSync   bool
+	}{
+		{name: "default syncs", durability: "", wantSync: true},
+		{name: "fsync", durability: types.DurabilityFsync, wantSync: true},
+		{name: "none", durability: types.DurabilityNone, wantSync: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src.txt")
+			dst := filepath.Join(dir, "out", "dst.txt")
+			mustWriteFile(t, src, "ledger")
+			mustMkdirAll(t, filepath.Dir(dst))
+
+			before := fsyncTime.Load()
+			if _, err := copyfileStream(src, dst, types.BackupOptions{Durability: tt.durability}, copyControl{}); err != nil {
+				t.Fatalf("copy: %v", err)
+			}
+			synced := fsyncTime.Load() != before
+			if synced != tt.wantSync {
+				t.Fatalf("synced = %v, want %v", synced, tt.wantSync)
+			}
+			assertFileContents(t, dst, "ledger")
+		})
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	if err := syncDir(t.TempDir()); err != nil {
+		t.Fatalf("syncDir: %v", err)
+	}
+	if runtime.GOOS == "windows" {
+		return // directories are not synced on Windows
+	}
+	if err := syncDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatalf("expected error for a missing folder")
+	}
+}
+
+func TestMkdirAllCreated(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "02Mar26", "scans", "sub")
+
+	created, err := mkdirAllCreated(dir)
+	if err != nil {
+		t.Fatalf("mkdirAllCreated: %v", err)
+	}
+	want := []string{
+		filepath.Join(root, "02Mar26"),
+		filepath.Join(root, "02Mar26", "scans"),
+		dir,
+	}
+	if !slices.Equal(created, want) {
+		t.Fatalf("created %v, want %v", created, want)
+	}
+	assertExists(t, dir)
+	if err := syncCreatedDirs(created); err != nil {
+		t.Fatalf("syncCreatedDirs: %v", err)
+	}
+
+	created, err = mkdirAllCreated(dir)
+	if err != nil || len(created) != 0 {
+		t.Fatalf("existing folder: created %v (err=%v), want none", created, err)
+	}
+}
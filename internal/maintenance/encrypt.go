@@ -0,0 +1,295 @@
+package maintenance
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"file-maintenance/internal/types"
+)
+
+// Encrypted backup format (version 2):
+//
+//	header:  "FMENC" | version (1 byte) | key ID (8 bytes) | salt (32 bytes) | nonce prefix (7 bytes)
+//	chunks:  AES-256-GCM sealed chunks of up to encChunkSize plaintext bytes
+//
+// Every file is sealed under its own key, derived with HKDF-SHA256 from the
+// configured key and the file's random salt (see fileKey). The configured key
+// is static across files and runs, so with it alone the random part of the
+// nonces would be the only thing keeping them apart, and too short to rule
+// out a repeat over the years; one repeated GCM nonce gives away both the
+// content and the ability to forge it. Under a per-file key a nonce only has
+// to be unique within the file, which the chunk counter guarantees.
+//
+// Each chunk's 12-byte nonce is the random nonce prefix, a 4-byte big-endian
+// chunk counter and a final-chunk flag (1 on the last chunk, else 0). The whole
+// header is authenticated with every chunk as additional data. Together this
+// means reordered, dropped, or appended chunks, a truncated file, and an edited
+// header all fail to decrypt instead of producing wrong plaintext.
+//
+// Every file ends with a chunk flagged final, even an empty file, which is a
+// single final chunk holding only the GCM tag.
+const (
+	encMagic          = "FMENC"
+	encVersion        = 2
+	encKeyIDLen       = 8
+	encSaltLen        = 32
+	encNoncePrefixLen = 7
+	encHeaderLen      = len(encMagic) + 1 + encKeyIDLen + encSaltLen + encNoncePrefixLen
+	encKeyInfo        = "file-maintenance backup file key v2"
+	encChunkSize      = 64 * 1024
+	encTagSize        = 16
+	encSuffix         = ".enc"
+	encAlgorithm      = "aes-256-gcm"
+)
+
+// errBackupDecrypt is returned when an encrypted backup does not authenticate:
+// it was modified, truncated, or encrypted with a different key.
+var errBackupDecrypt = errors.New("encrypted backup failed authentication")
+
+// encryptedSize is the stored size of plainBytes of content after encryption:
+// the header plus one tag per chunk. Empty content still has one chunk.
+func encryptedSize(plainBytes uint64) uint64 {
+	chunks := (plainBytes + encChunkSize - 1) / encChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return uint64(encHeaderLen) + plainBytes + chunks*encTagSize
+}
+
+// newBackupAEAD returns AES-256-GCM under k, the file key derived from the
+// configured key with ID id.
+func newBackupAEAD(k []byte, id string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("backup key %s: %w", id, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileKey derives the key of one encrypted file from the configured key and
+// the file's salt.
+func fileKey(key *types.BackupKey, salt []byte) ([]byte, error) {
+	k, err := hkdf.Key(sha256.New, key.Key, salt, encKeyInfo, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive file key: %w", err)
+	}
+	return k, nil
+}
+
+// chunkEncrypter seals everything written to it in encChunkSize chunks.
+//
+// A full chunk is only sealed once more data arrives, so Close can always flag
+// the last chunk as final. Close must be called; it does not close w.
+type chunkEncrypter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	nonce   []byte
+	counter uint32
+	buf     []byte
+	sealed  []byte
+}
+
+// newEncryptWriter writes a header with a fresh salt for key to w and returns
+// a writer that encrypts everything written to it under the file key.
+func newEncryptWriter(w io.Writer, key *types.BackupKey) (io.WriteCloser, error) {
+	keyID, err := hex.DecodeString(key.ID)
+	if err != nil || len(keyID) != encKeyIDLen {
+		return nil, fmt.Errorf("invalid backup key ID %q", key.ID)
+	}
+
+	// Salt and nonce prefix are drawn together.
+	random := make([]byte, encSaltLen+encNoncePrefixLen)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("generate salt and nonce: %w", err)
+	}
+	salt, prefix := random[:encSaltLen], random[encSaltLen:]
+
+	k, err := fileKey(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newBackupAEAD(k, key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, encHeaderLen)
+	header = append(header, encMagic...)
+	header = append(header, encVersion)
+	header = append(header, keyID...)
+	header = append(header, random...)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, prefix)
+
+	return &chunkEncrypter{
+		w:      w,
+		aead:   aead,
+		header: header,
+		nonce:  nonce,
+		buf:    make([]byte, 0, encChunkSize),
+		sealed: make([]byte, 0, encChunkSize+encTagSize),
+	}, nil
+}
+
+func (e *chunkEncrypter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if len(e.buf) == encChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):encChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the buffered data as the final chunk.
+func (e *chunkEncrypter) Close() error {
+	return e.seal(true)
+}
+
+func (e *chunkEncrypter) seal(final bool) error {
+	if e.counter == ^uint32(0) {
+		return fmt.Errorf("encrypted backup too large")
+	}
+	setChunkNonce(e.nonce, e.counter, final)
+	e.sealed = e.aead.Seal(e.sealed[:0], e.nonce, e.buf, e.header)
+	if _, err := e.w.Write(e.sealed); err != nil {
+		return err
+	}
+	e.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// setChunkNonce fills in the counter and final flag after the nonce prefix.
+func setChunkNonce(nonce []byte, counter uint32, final bool) {
+	binary.BigEndian.PutUint32(nonce[encNoncePrefixLen:], counter)
+	nonce[len(nonce)-1] = 0
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+}
+
+// chunkDecrypter reverses chunkEncrypter. Data from a chunk is only returned
+// after the chunk authenticated, and io.EOF only after the final chunk did.
+type chunkDecrypter struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  []byte
+	nonce   []byte
+	counter uint32
+	chunk   []byte
+	plain   []byte
+	done    bool
+}
+
+// newDecryptReader reads the header from r, checks that it was written with
+// key, and returns a reader of the decrypted content.
+func newDecryptReader(r io.Reader, key *types.BackupKey) (io.Reader, error) {
+	header := make([]byte, encHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: missing header", errBackupDecrypt)
+	}
+	if string(header[:len(encMagic)]) != encMagic {
+		return nil, fmt.Errorf("not an encrypted backup")
+	}
+	if v := header[len(encMagic)]; v != encVersion {
+		return nil, fmt.Errorf("unsupported encrypted backup version %d", v)
+	}
+
+	idStart := len(encMagic) + 1
+	fileKeyID := header[idStart : idStart+encKeyIDLen]
+	if key == nil {
+		return nil, fmt.Errorf("backup is encrypted with key %x but no [backup] encrypt-key is configured", fileKeyID)
+	}
+	if wantID, err := hex.DecodeString(key.ID); err != nil || !bytes.Equal(wantID, fileKeyID) {
+		return nil, fmt.Errorf("backup is encrypted with key %x, but the configured key is %s", fileKeyID, key.ID)
+	}
+
+	saltStart := idStart + encKeyIDLen
+	k, err := fileKey(key, header[saltStart:saltStart+encSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newBackupAEAD(k, key.ID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, header[saltStart+encSaltLen:])
+
+	return &chunkDecrypter{
+		r:      bufio.NewReaderSize(r, encChunkSize+encTagSize),
+		aead:   aead,
+		header: header,
+		nonce:  nonce,
+		chunk:  make([]byte, encChunkSize+encTagSize),
+	}, nil
+}
+
+func (d *chunkDecrypter) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next reads and opens one chunk. A chunk is final when it is short or
+// nothing follows it; the final flag in its nonce must agree, or it does
+// not authenticate.
+func (d *chunkDecrypter) next() error {
+	n, err := io.ReadFull(d.r, d.chunk)
+	final := false
+	switch {
+	case err == io.EOF:
+		return fmt.Errorf("%w: truncated", errBackupDecrypt)
+	case err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		return err
+	default:
+		if _, err := d.r.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	setChunkNonce(d.nonce, d.counter, final)
+	plain, err := d.aead.Open(d.chunk[:0], d.nonce, d.chunk[:n], d.header)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d", errBackupDecrypt, d.counter)
+	}
+	d.counter++
+	d.plain = plain
+	d.done = final
+	return nil
+}
@@ -0,0 +1,223 @@
+package maintenance
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// testBackupKey returns a fixed AES-256 key. IDs only need to be 8 hex-encoded
+// bytes here; config.loadBackupKey derives real ones from the key.
+func testBackupKey(fill byte, id string) *types.BackupKey {
+	return &types.BackupKey{ID: id, Key: bytes.Repeat([]byte{fill}, 32)}
+}
+
+func encryptBytes(t *testing.T, key *types.BackupKey, plain []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := newEncryptWriter(&out, key)
+	if err != nil {
+		t.Fatalf("new encrypt writer: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close encrypt writer: %v", err)
+	}
+	return out.Bytes()
+}
+
+func decryptBytes(key *types.BackupKey, stored []byte) ([]byte, error) {
+	r, err := newDecryptReader(bytes.NewReader(stored), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptStream_RoundTrip(t *testing.T) {
+	key := testBackupKey(1, "0123456789abcdef")
+
+	for _, size := range []int{0, 1, encChunkSize - 1, encChunkSize, encChunkSize + 1, 3*encChunkSize + 5} {
+		plain := make([]byte, size)
+		for i := range plain {
+			plain[i] = byte(i * 7)
+		}
+
+		stored := encryptBytes(t, key, plain)
+		if uint64(len(stored)) != encryptedSize(uint64(size)) {
+			t.Fatalf("size %d: encryptedSize=%d, actual %d", size, encryptedSize(uint64(size)), len(stored))
+		}
+		if size > 16 && bytes.Contains(stored, plain[:16]) {
+			t.Fatalf("size %d: stored bytes contain plaintext", size)
+		}
+
+		got, err := decryptBytes(key, stored)
+		if err != nil {
+			t.Fatalf("size %d: decrypt: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: round trip mismatch", size)
+		}
+	}
+}
+
+func TestEncryptStream_FreshKeyPerFile(t *testing.T) {
+	key := testBackupKey(1, "0123456789abcdef")
+	plain := []byte("same content twice")
+
+	a, b := encryptBytes(t, key, plain), encryptBytes(t, key, plain)
+	saltStart := len(encMagic) + 1 + encKeyIDLen
+	if bytes.Equal(a[saltStart:saltStart+encSaltLen], b[saltStart:saltStart+encSaltLen]) {
+		t.Fatalf("two files share a salt")
+	}
+	if bytes.Equal(a[encHeaderLen:], b[encHeaderLen:]) {
+		t.Fatalf("two files share their ciphertext")
+	}
+}
+
+func TestEncryptStream_RejectsTampering(t *testing.T) {
+	key := testBackupKey(1, "0123456789abcdef")
+	plain := bytes.Repeat([]byte("log line\n"), 2*encChunkSize/9+100) // spans 3 chunks
+	stored := encryptBytes(t, key, plain)
+	firstChunkEnd := encHeaderLen + encChunkSize + encTagSize
+
+	tests := []struct {
+		name   string
+		key    *types.BackupKey
+		stored func() []byte
+	}{
+		{name: "flipped ciphertext bit", key: key, stored: func() []byte {
+			b := bytes.Clone(stored)
+			b[encHeaderLen+10] ^= 1
+			return b
+		}},
+		{name: "edited header nonce", key: key, stored: func() []byte {
+			b := bytes.Clone(stored)
+			b[encHeaderLen-1] ^= 1
+			return b
+		}},
+		{name: "edited header salt", key: key, stored: func() []byte {
+			b := bytes.Clone(stored)
+			b[encHeaderLen-encNoncePrefixLen-1] ^= 1
+			return b
+		}},
+		{name: "truncated at chunk boundary", key: key, stored: func() []byte {
+			return bytes.Clone(stored[:firstChunkEnd])
+		}},
+		{name: "truncated mid chunk", key: key, stored: func() []byte {
+			return bytes.Clone(stored[:len(stored)-5])
+		}},
+		{name: "appended data", key: key, stored: func() []byte {
+			return append(bytes.Clone(stored), "extra"...)
+		}},
+		{name: "header only", key: key, stored: func() []byte {
+			return bytes.Clone(stored[:encHeaderLen])
+		}},
+		{name: "different key with same ID", key: testBackupKey(2, "0123456789abcdef"), stored: func() []byte {
+			return stored
+		}},
+		{name: "different key ID", key: testBackupKey(1, "fedcba9876543210"), stored: func() []byte {
+			return stored
+		}},
+		{name: "other format version", key: key, stored: func() []byte {
+			b := bytes.Clone(stored)
+			b[len(encMagic)] = 1
+			return b
+		}},
+		{name: "not encrypted", key: key, stored: func() []byte {
+			return plain
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptBytes(tt.key, tt.stored())
+			if err == nil {
+				t.Fatalf("expected decryption error, got %d bytes", len(got))
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_EncryptedBackupAndRestore(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Compress = types.CompressGzip
+	cfg.Backup.Key = testBackupKey(1, "0123456789abcdef")
+
+	p := filepath.Join(src, "payroll.csv")
+	payload := strings.Repeat("employee,salary\n", 100)
+	mustWriteFile(t, p, payload)
+	mustSetAgeDays(t, p, 10)
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	assertNotExists(t, p)
+
+	today := time.Now().Format(backupDateLayout)
+	stored := filepath.Join(backup, today, filepath.Base(src), "payroll.csv.gz.enc")
+	b, err := os.ReadFile(stored)
+	if err != nil {
+		t.Fatalf("read encrypted backup: %v", err)
+	}
+	if !bytes.HasPrefix(b, []byte(encMagic)) || bytes.Contains(b, []byte("employee")) {
+		t.Fatalf("backup is not encrypted")
+	}
+
+	records, _, err := ReadBackupManifest(filepath.Join(backup, today, backupManifestName))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("read manifest: records=%d err=%v", len(records), err)
+	}
+	rec := records[0]
+	if rec.Encryption != encAlgorithm || rec.KeyID != "0123456789abcdef" || rec.Compression != "gzip" {
+		t.Fatalf("unexpected manifest record: %+v", rec)
+	}
+	want, _ := hashFile(stored, types.HashSHA256)
+	if rec.Hash == want {
+		t.Fatalf("manifest checksum must be of the plaintext, not the stored bytes")
+	}
+
+	// Without the key nothing is restored.
+	summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+	if err != nil || summary.Failed != 1 {
+		t.Fatalf("restore without key: summary=%+v err=%v", summary, err)
+	}
+	assertNotExists(t, p)
+
+	target := filepath.Join(root, "restored")
+	opts := types.RestoreOptions{Dates: today, Target: target, Key: cfg.Backup.Key}
+	if _, err := Restore(pathconfig, backup, opts, log); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	assertFileContents(t, filepath.Join(target, filepath.Base(src), "payroll.csv"), payload)
+}
+
+func TestHashStored_EncryptedWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a.txt")
+	mustWriteFile(t, src, "alpha")
+
+	key := testBackupKey(1, "0123456789abcdef")
+	stored := filepath.Join(dir, "a.txt.enc")
+	if _, err := copyfileStream(src, stored, types.BackupOptions{Hash: types.HashSHA256, Verify: true, Key: key}, copyControl{}); err != nil {
+		t.Fatalf("encrypted copy: %v", err)
+	}
+
+	_, err := hashStored(stored, types.HashSHA256, storedFormat{key: testBackupKey(2, "0123456789abcdef")}, copyControl{})
+	if !errors.Is(err, errBackupDecrypt) {
+		t.Fatalf("expected errBackupDecrypt, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build darwin
+
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// ageBasisSupported reports which stat-based age bases macOS can answer.
+func ageBasisSupported(basis types.AgeBasis) bool {
+	switch basis {
+	case types.AgeChanged, types.AgeAccessed, types.AgeCreated:
+		return true
+	default:
+		return false
+	}
+}
+
+// statTime returns change, access, or birth time from the stat data in info.
+func statTime(path string, info os.FileInfo, basis types.AgeBasis) (time.Time, error) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no stat data for %s: %w", path, errAgeBasisUnsupported)
+	}
+
+	switch basis {
+	case types.AgeChanged:
+		return time.Unix(st.Ctimespec.Unix()), nil
+	case types.AgeAccessed:
+		return time.Unix(st.Atimespec.Unix()), nil
+	case types.AgeCreated:
+		return time.Unix(st.Birthtimespec.Unix()), nil
+	default:
+		return time.Time{}, fmt.Errorf("%s: %w", basis, errAgeBasisUnsupported)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build linux
+
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"file-maintenance/internal/types"
+)
+
+// ageBasisSupported reports which stat-based age bases Linux can answer.
+// Birth time additionally depends on the filesystem (see statTime).
+func ageBasisSupported(basis types.AgeBasis) bool {
+	switch basis {
+	case types.AgeChanged, types.AgeAccessed, types.AgeCreated:
+		return true
+	default:
+		return false
+	}
+}
+
+// statTime returns change, access, or birth time for path.
+//
+// Change and access time come from the stat data already in info. Birth time
+// needs statx(2); filesystems that do not record it (or older kernels) report
+// it as unavailable, and the file is not selected.
+func statTime(path string, info os.FileInfo, basis types.AgeBasis) (time.Time, error) {
+	if basis == types.AgeCreated {
+		var stx unix.Statx_t
+		if err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx); err != nil {
+			return time.Time{}, fmt.Errorf("statx %s: %w", path, err)
+		}
+		if stx.Mask&unix.STATX_BTIME == 0 {
+			return time.Time{}, fmt.Errorf("birth time not recorded for %s: %w", path, errAgeBasisUnsupported)
+		}
+		return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)), nil
+	}
+
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no stat data for %s: %w", path, errAgeBasisUnsupported)
+	}
+
+	switch basis {
+	case types.AgeChanged:
+		return time.Unix(st.Ctim.Unix()), nil
+	case types.AgeAccessed:
+		return time.Unix(st.Atim.Unix()), nil
+	default:
+		return time.Time{}, fmt.Errorf("%s: %w", basis, errAgeBasisUnsupported)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !windows
+
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// ageBasisSupported reports that only modification time and file-name dates
+// are available on this platform.
+func ageBasisSupported(basis types.AgeBasis) bool {
+	return false
+}
+
+func statTime(path string, info os.FileInfo, basis types.AgeBasis) (time.Time, error) {
+	return time.Time{}, fmt.Errorf("%s: %w", basis, errAgeBasisUnsupported)
+}
@@ -0,0 +1,42 @@
+//go:build windows
+
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// ageBasisSupported reports which stat-based age bases Windows can answer.
+//
+// Windows exposes creation and last-access times through the file attribute
+// data, but not a POSIX-style change time.
+func ageBasisSupported(basis types.AgeBasis) bool {
+	switch basis {
+	case types.AgeAccessed, types.AgeCreated:
+		return true
+	default:
+		return false
+	}
+}
+
+// statTime returns access or creation time from the attribute data in info.
+func statTime(path string, info os.FileInfo, basis types.AgeBasis) (time.Time, error) {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no attribute data for %s: %w", path, errAgeBasisUnsupported)
+	}
+
+	switch basis {
+	case types.AgeAccessed:
+		return time.Unix(0, attrs.LastAccessTime.Nanoseconds()), nil
+	case types.AgeCreated:
+		return time.Unix(0, attrs.CreationTime.Nanoseconds()), nil
+	default:
+		return time.Time{}, fmt.Errorf("%s: %w", basis, errAgeBasisUnsupported)
+	}
+}
@@ -0,0 +1,135 @@
+package maintenance
+
+import (
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// pathFilter applies the include/exclude glob patterns of one [paths] entry.
+//
+// Pattern rules:
+//   - Patterns are matched against the path relative to the configured folder,
+//     using "/" as the separator on every OS.
+//   - A pattern without "/" (e.g. "*.log") matches the base name at any depth.
+//   - A pattern with "/" (e.g. "reports/*.pdf") matches the whole relative path.
+//   - "**" as a path segment matches zero or more directories
+//     (e.g. "**/keep/**" matches anything inside any "keep" directory).
+//   - On Windows, matching is case-insensitive.
+//
+// Semantics:
+//   - Exclude patterns apply to files and directories. A directory that matches
+//     an exclude pattern is pruned, so its subtree is never walked.
+//   - Include patterns apply to files only. When at least one include pattern is
+//     set, a file must match one of them to be eligible.
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+// newPathFilter normalizes patterns once so matching stays cheap inside
+// the WalkDir callback.
+func newPathFilter(include, exclude []string) pathFilter {
+	return pathFilter{
+		include: normalizePatterns(include),
+		exclude: normalizePatterns(exclude),
+	}
+}
+
+// empty reports whether the filter has no patterns (every file is eligible).
+func (f pathFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// excludesDir reports whether the directory at rel (relative to the configured
+// folder) should be pruned from the walk.
+func (f pathFilter) excludesDir(rel string) bool {
+	rel = normalizeRel(rel)
+	return matchAny(f.exclude, rel)
+}
+
+// allowsFile reports whether the file at rel (relative to the configured folder)
+// is eligible for processing.
+func (f pathFilter) allowsFile(rel string) bool {
+	rel = normalizeRel(rel)
+	if matchAny(f.exclude, rel) {
+		return false
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	return matchAny(f.include, rel)
+}
+
+// matchAny reports whether rel matches at least one pattern.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a normalized pattern against a normalized relative path.
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, err := path.Match(pattern, path.Base(rel))
+		return err == nil && ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches pattern segments against path segments, letting "**"
+// consume zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], segments[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+func normalizePatterns(patterns []string) []string {
+	out := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		out = append(out, strings.Trim(foldCase(filepath.ToSlash(p)), "/"))
+	}
+	return out
+}
+
+func normalizeRel(rel string) string {
+	return foldCase(filepath.ToSlash(rel))
+}
+
+// foldCase lower-cases on Windows, where file names are case-insensitive.
+func foldCase(s string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToLower(s)
+	}
+	return s
+}
@@ -0,0 +1,94 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"testing"
+
+	"file-maintenance/internal/types"
+)
+
+func TestPathFilter_Table(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		rel       string
+		isDir     bool
+		wantAllow bool
+	}{
+		{name: "no patterns allows everything", rel: "a/b.txt", wantAllow: true},
+		{name: "include base name at any depth", include: []string{"*.log", "*.tmp"}, rel: "x/y/app.log", wantAllow: true},
+		{name: "include rejects non-matching file", include: []string{"*.log"}, rel: "x/report.pdf", wantAllow: false},
+		{name: "exclude base name", exclude: []string{"*.db"}, rel: "data/state.db", wantAllow: false},
+		{name: "exclude wins over include", include: []string{"*.db"}, exclude: []string{"*.db"}, rel: "state.db", wantAllow: false},
+		{name: "double star excludes files inside keep", exclude: []string{"**/keep/**"}, rel: "a/keep/b/c.txt", wantAllow: false},
+		{name: "double star excludes top-level keep", exclude: []string{"**/keep/**"}, rel: "keep/c.txt", wantAllow: false},
+		{name: "double star leaves similar names", exclude: []string{"**/keep/**"}, rel: "a/keeper/c.txt", wantAllow: true},
+		{name: "anchored pattern matches whole path", include: []string{"reports/*.pdf"}, rel: "reports/q1.pdf", wantAllow: true},
+		{name: "anchored pattern does not match deeper", include: []string{"reports/*.pdf"}, rel: "old/reports/q1.pdf", wantAllow: false},
+		{name: "directory pruned by double star", exclude: []string{"**/keep/**"}, rel: "a/keep", isDir: true, wantAllow: false},
+		{name: "directory pruned by base name", exclude: []string{"cache"}, rel: "a/cache", isDir: true, wantAllow: false},
+		{name: "include never prunes directories", include: []string{"*.log"}, rel: "a/sub", isDir: true, wantAllow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPathFilter(tt.include, tt.exclude)
+			rel := filepath.FromSlash(tt.rel)
+
+			var got bool
+			if tt.isDir {
+				got = !f.excludesDir(rel)
+			} else {
+				got = f.allowsFile(rel)
+			}
+			if got != tt.wantAllow {
+				t.Fatalf("want allow=%v, got %v", tt.wantAllow, got)
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_IncludeExcludeFilters(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	files := map[string]bool{ // relative path -> expect deleted
+		"app.log":             true,
+		"nested/trace.tmp":    true,
+		"notes.txt":           false, // not included
+		"state.db":            false, // excluded
+		"keep/inner/old.log":  false, // excluded subtree
+		"nested/keep/old.log": false, // excluded subtree
+	}
+	for rel := range files {
+		p := filepath.Join(src, filepath.FromSlash(rel))
+		mustMkdirAll(t, filepath.Dir(p))
+		mustWriteFile(t, p, "x")
+		mustSetAgeDays(t, p, 10)
+	}
+
+	pathconfig := []types.PathConfig{
+		{
+			Path:    src,
+			Backup:  false,
+			IsDir:   true,
+			Include: []string{"*.log", "*.tmp", "*.db"},
+			Exclude: []string{"**/keep/**", "*.db"},
+		},
+	}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	for rel, wantDeleted := range files {
+		p := filepath.Join(src, filepath.FromSlash(rel))
+		if wantDeleted {
+			assertNotExists(t, p)
+		} else {
+			assertExists(t, p)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package maintenance
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupManifestName is the file written into each dated backup folder.
+const backupManifestName = "manifest.jsonl"
+
+// BackupRecord is one line of a dated folder's manifest.jsonl.
+//
+// Each record describes one file this tool backed up (or found already backed
+// up with identical content) and is enough to audit the run or restore the file
+// to its original location without guessing the source root.
+type BackupRecord struct {
+	RunID         string    `json:"run_id"`
+	Time          time.Time `json:"time"`
+	SourcePath    string    `json:"source_path"`
+	BackupPath    string    `json:"backup_path"`
+	SizeBytes     uint64    `json:"size_bytes"`
+	SourceModTime time.Time `json:"source_mod_time"`
+	Hash          string    `json:"hash"`
+	HashAlgorithm string    `json:"hash_algorithm"`
+	ConfigPath    string    `json:"config_path"`
+	FolderRoot    string    `json:"folder_root"`
+
+	// Reused is true when no copy was made because BackupPath already held
+	// identical content (see resolveBackupTarget).
+	Reused bool `json:"reused,omitempty"`
+
+	// Moved is true when the file was renamed into BackupPath on the same
+	// filesystem instead of being copied (see [backup] move). Its content was
+	// never read, so Hash is empty.
+	Moved bool `json:"moved,omitempty"`
+
+	// SourceMode holds the source's permission bits in octal (e.g. "0200")
+	// when its backup copy has others: a backup is always readable by its
+	// owner (see backupMode). Restore applies it instead of the copy's bits.
+	SourceMode string `json:"source_mode,omitempty"`
+
+	// Compression is set (e.g. "gzip") when BackupPath holds a compressed
+	// copy. Hash is always of the original, uncompressed content.
+	Compression string `json:"compression,omitempty"`
+
+	// Encryption is set (e.g. "aes-256-gcm") when BackupPath is encrypted,
+	// with KeyID identifying the key (see types.BackupKey). Hash is of the
+	// plaintext.
+	Encryption string `json:"encryption,omitempty"`
+	KeyID      string `json:"key_id,omitempty"`
+
+	// Object is set in the objects layout: the stored content, relative to
+	// the backup root and slash-separated (see objectRelPath). BackupPath is
+	// then the file's logical place in the dated layout; nothing is stored
+	// there.
+	Object string `json:"object,omitempty"`
+
+	// Archive is set in the archive layout (e.g. "zip"): BackupPath is then
+	// the archive and Member the file's slash-separated name inside it.
+	Archive string `json:"archive,omitempty"`
+	Member  string `json:"member,omitempty"`
+}
+
+// backupManifest appends BackupRecords to <backupRoot>/<DDMmmYY>/manifest.jsonl.
+//
+// Durability model:
+//   - The file is opened with O_APPEND and each record is written with a single
+//     Write call followed by Sync, so a record is on disk before the source file
+//     it describes is deleted.
+//   - A crash can at worst leave a truncated final line; ReadBackupManifest skips
+//     lines that do not parse, so earlier records stay usable. The first record
+//     appended to such a file starts on a new line (see endsInNewline), so it is
+//     not glued onto the truncated one and lost with it.
+//   - Several runs (or a run that crosses midnight) append to the same files;
+//     RunID tells their records apart.
+//
+// Files are kept open for the whole run and closed by close(). A mutex guards
+// the handles even though only the processor goroutine appends today.
+type backupManifest struct {
+	mu         sync.Mutex
+	backupRoot string
+	runID      string
+	files      map[string]*os.File // manifest path -> open handle
+}
+
+func newBackupManifest(backupRoot, runID string) *backupManifest {
+	return &backupManifest{
+		backupRoot: backupRoot,
+		runID:      runID,
+		files:      make(map[string]*os.File),
+	}
+}
+
+// append writes rec to the manifest of the dated folder that contains
+// rec.BackupPath, filling in RunID and Time.
+func (m *backupManifest) append(rec BackupRecord) error {
+	manifestPath, err := manifestPathFor(m.backupRoot, rec.BackupPath)
+	if err != nil {
+		return err
+	}
+
+	rec.RunID = m.runID
+	rec.Time = time.Now()
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode manifest record: %w", err)
+	}
+	line = append(line, '\n')
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, ok := m.files[manifestPath]
+	if !ok {
+		// In the objects layout nothing else creates the dated folder.
+		if err := os.MkdirAll(filepath.Dir(manifestPath), 0o755); err != nil {
+			return fmt.Errorf("create manifest folder: %w", err)
+		}
+		f, err = os.OpenFile(manifestPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		terminated, err := endsInNewline(manifestPath)
+		if err != nil {
+			_ = f.Close()
+			return fmt.Errorf("read manifest %s: %w", manifestPath, err)
+		}
+		if !terminated {
+			// Still one Write call, so the record is never split.
+			line = append([]byte{'\n'}, line...)
+		}
+		m.files[manifestPath] = f
+	}
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("write manifest %s: %w", manifestPath, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync manifest %s: %w", manifestPath, err)
+	}
+
+	return nil
+}
+
+// endsInNewline reports whether the file at path is empty or ends in '\n',
+// i.e. whether a record appended to it starts on a line of its own.
+func endsInNewline(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() == 0 {
+		return true, nil
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
+
+// close closes every manifest opened during the run.
+func (m *backupManifest) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var firstErr error
+	for p, f := range m.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("close manifest %s: %w", p, err)
+		}
+		delete(m.files, p)
+	}
+	return firstErr
+}
+
+// manifestPathFor returns <backupRoot>/<first segment of backupPath>/manifest.jsonl.
+//
+// The dated folder is taken from the backup path itself rather than from
+// time.Now(), so a record always lands next to the file it describes, even when
+// a run crosses midnight.
+func manifestPathFor(backupRoot, backupPath string) (string, error) {
+	rel, err := filepath.Rel(backupRoot, backupPath)
+	if err != nil {
+		return "", fmt.Errorf("backup path %s is not under %s: %w", backupPath, backupRoot, err)
+	}
+
+	dateFolder, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	if dateFolder == "" || dateFolder == "." || dateFolder == ".." || dateFolder == filepath.ToSlash(rel) {
+		return "", fmt.Errorf("backup path %s is not inside a dated folder of %s", backupPath, backupRoot)
+	}
+
+	return filepath.Join(backupRoot, dateFolder, backupManifestName), nil
+}
+
+// ReadBackupManifest loads every record from a manifest.jsonl file.
+//
+// Lines that do not parse (for example a final line truncated by a crash) are
+// skipped and counted in skipped, so one damaged record never hides the rest.
+func ReadBackupManifest(path string) (records []BackupRecord, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		var rec BackupRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return records, skipped, fmt.Errorf("read manifest %s: %w", path, err)
+	}
+
+	return records, skipped, nil
+}
+
+// newRunID returns an identifier for one worker run: the start time plus a
+// short random suffix, e.g. "20260130T021500-9f3c2a1b". It sorts by start time
+// and stays unique when two runs start in the same second.
+func newRunID(start time.Time) string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return start.Format("20060102T150405.000000000")
+	}
+	return start.Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,177 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestManifestPathFor_Table(t *testing.T) {
+	root := filepath.Join("D:", "backups")
+
+	tests := []struct {
+		name       string
+		backupPath string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "nested file",
+			backupPath: filepath.Join(root, "30Jan26", "scans", "2026", "a.pdf"),
+			want:       filepath.Join(root, "30Jan26", backupManifestName),
+		},
+		{name: "file directly under root", backupPath: filepath.Join(root, "a.pdf"), wantErr: true},
+		{name: "outside root", backupPath: filepath.Join("D:", "other", "30Jan26", "a.pdf"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := manifestPathFor(root, tt.backupPath)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (got=%q)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadBackupManifest_SkipsTruncatedLine(t *testing.T) {
+	p := filepath.Join(t.TempDir(), backupManifestName)
+	mustWriteFile(t, p, `{"run_id":"r1","source_path":"a"}`+"\n"+`{"run_id":"r1","source_path":"b"}`+"\n"+`{"run_id":"r1","sou`)
+
+	records, skipped, err := ReadBackupManifest(p)
+	if err != nil {
+		t.Fatalf("ReadBackupManifest: %v", err)
+	}
+	if len(records) != 2 || skipped != 1 {
+		t.Fatalf("want 2 records and 1 skipped line, got %d records, %d skipped", len(records), skipped)
+	}
+	if records[1].SourcePath != "b" {
+		t.Fatalf("unexpected record order: %+v", records)
+	}
+}
+
+func TestBackupManifest_AppendAfterTruncatedLine(t *testing.T) {
+	root := t.TempDir()
+	day := filepath.Join(root, "30Jan26")
+	p := filepath.Join(day, backupManifestName)
+	mustMkdirAll(t, day)
+	mustWriteFile(t, p, `{"run_id":"r1","source_path":"a"}`+"\n"+`{"run_id":"r1","sou`)
+
+	m := newBackupManifest(root, "r2")
+	for _, name := range []string{"b", "c"} {
+		if err := m.append(BackupRecord{SourcePath: name, BackupPath: filepath.Join(day, "scans", name)}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	if err := m.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	records, skipped, err := ReadBackupManifest(p)
+	if err != nil {
+		t.Fatalf("ReadBackupManifest: %v", err)
+	}
+	if len(records) != 3 || skipped != 1 {
+		t.Fatalf("want 3 records and 1 skipped line, got %d records, %d skipped", len(records), skipped)
+	}
+	if records[1].SourcePath != "b" || records[2].SourcePath != "c" {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
+
+func TestWorker_Integration_WritesBackupManifest(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Move = false // the sandbox is one filesystem; test the copy path
+
+	files := []string{filepath.Join(src, "a.txt"), filepath.Join(src, "sub", "b.txt")}
+	for _, p := range files {
+		mustMkdirAll(t, filepath.Dir(p))
+		mustWriteFile(t, p, "contents of "+filepath.Base(p))
+		mustSetAgeDays(t, p, 10)
+	}
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	manifestPath := filepath.Join(backup, time.Now().Format("02Jan06"), backupManifestName)
+	records, skipped, err := ReadBackupManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("ReadBackupManifest: %v", err)
+	}
+	if len(records) != len(files) || skipped != 0 {
+		t.Fatalf("want %d records, got %d (skipped %d)", len(files), len(records), skipped)
+	}
+
+	for _, rec := range records {
+		if rec.RunID == "" || rec.RunID != records[0].RunID {
+			t.Fatalf("expected one shared run ID, got %+v", records)
+		}
+		if rec.ConfigPath != src || rec.FolderRoot != src || rec.HashAlgorithm != "sha256" || rec.Reused {
+			t.Fatalf("unexpected record: %+v", rec)
+		}
+		assertNotExists(t, rec.SourcePath)
+
+		want, err := hashFile(rec.BackupPath, types.HashSHA256)
+		if err != nil {
+			t.Fatalf("hash backup: %v", err)
+		}
+		if rec.Hash != want {
+			t.Fatalf("record hash %s does not match backup %s", rec.Hash, want)
+		}
+
+		info, err := os.Stat(rec.BackupPath)
+		if err != nil || uint64(info.Size()) != rec.SizeBytes {
+			t.Fatalf("record size %d does not match backup (err=%v)", rec.SizeBytes, err)
+		}
+	}
+}
+
+func TestWorker_Integration_ManifestRecordsReusedBackup(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+
+	p := filepath.Join(src, "a.txt")
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+
+	// The same content deleted twice in one day: the second run reuses the copy.
+	for i := 0; i < 2; i++ {
+		mustWriteFile(t, p, "same")
+		mustSetAgeDays(t, p, 10)
+		if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+			t.Fatalf("worker error: %v", err)
+		}
+	}
+
+	records, _, err := ReadBackupManifest(filepath.Join(backup, time.Now().Format("02Jan06"), backupManifestName))
+	if err != nil {
+		t.Fatalf("ReadBackupManifest: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("want 2 records, got %d", len(records))
+	}
+	if records[0].RunID == records[1].RunID {
+		t.Fatalf("expected distinct run IDs, got %q twice", records[0].RunID)
+	}
+	if records[0].Reused || !records[1].Reused || records[0].BackupPath != records[1].BackupPath {
+		t.Fatalf("expected second run to reuse the first copy: %+v", records)
+	}
+}
@@ -0,0 +1,90 @@
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// fileMetadata holds the attributes of a file, besides its content, that
+// backups and restores carry over (see readMetadata and applyMetadata).
+type fileMetadata struct {
+	modTime    time.Time
+	accessTime time.Time
+	mode       os.FileMode // permission bits only
+
+	// owner and xattrs are only read on Linux, and only when running as
+	// root: an unprivileged process can neither give a file away nor set
+	// most extended attribute namespaces.
+	owner  *fileOwner
+	xattrs map[string][]byte
+}
+
+type fileOwner struct {
+	uid, gid int
+}
+
+// readMetadata reads the metadata of path. It should be called before the
+// file is read, since reading it may update its access time.
+//
+// Only a failed stat is an error. Attributes the platform cannot report are
+// left empty and are then not applied; a missing access time falls back to the
+// modification time.
+func readMetadata(path string) (fileMetadata, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileMetadata{}, err
+	}
+
+	md := fileMetadata{
+		modTime:    info.ModTime(),
+		accessTime: info.ModTime(),
+		mode:       info.Mode().Perm(),
+	}
+	if atime, err := statTime(path, info, types.AgeAccessed); err == nil {
+		md.accessTime = atime
+	}
+	readPlatformMetadata(path, info, &md)
+
+	return md, nil
+}
+
+// backupMode returns the permission bits given to the backup of a file with
+// mode: the source's, plus read for the owner. A backup the tool cannot read
+// could not be compared with a new copy, verified, or restored. The source's
+// exact bits are then kept in its manifest record (BackupRecord.SourceMode)
+// and come back on restore.
+func backupMode(mode os.FileMode) os.FileMode {
+	return mode | 0o400
+}
+
+// formatMode and parseMode convert permission bits to and from the octal form
+// of BackupRecord.SourceMode, e.g. "0200".
+func formatMode(mode os.FileMode) string {
+	return fmt.Sprintf("%04o", uint32(mode.Perm()))
+}
+
+func parseMode(s string) (os.FileMode, error) {
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return os.FileMode(v).Perm(), nil
+}
+
+// applyMetadata sets md on path, best effort: every attribute is tried, and
+// one error per attribute that could not be set is returned for the caller to
+// log. None of them makes the copy itself invalid.
+//
+// Times are set last, since changing other attributes may touch them on some
+// filesystems.
+func applyMetadata(path string, md fileMetadata) []error {
+	errs := applyPlatformMetadata(path, md)
+	if err := os.Chtimes(path, md.accessTime, md.modTime); err != nil {
+		errs = append(errs, fmt.Errorf("file times: %w", err))
+	}
+	return errs
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// readPlatformMetadata adds ownership and extended attributes when running
+// as root. Attributes that cannot be read are skipped.
+func readPlatformMetadata(path string, info os.FileInfo, md *fileMetadata) {
+	if os.Geteuid() != 0 {
+		return
+	}
+
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		md.owner = &fileOwner{uid: int(st.Uid), gid: int(st.Gid)}
+	}
+
+	names, err := listXattrs(path)
+	if err != nil || len(names) == 0 {
+		return
+	}
+	md.xattrs = make(map[string][]byte, len(names))
+	for _, name := range names {
+		if value, err := getXattr(path, name); err == nil {
+			md.xattrs[name] = value
+		}
+	}
+}
+
+// applyPlatformMetadata sets ownership, permission bits, and extended
+// attributes, in that order: chown clears set-user-ID and set-group-ID bits,
+// and an extended attribute such as an ACL may depend on the mode.
+func applyPlatformMetadata(path string, md fileMetadata) []error {
+	var errs []error
+
+	if md.owner != nil {
+		if err := os.Chown(path, md.owner.uid, md.owner.gid); err != nil {
+			errs = append(errs, fmt.Errorf("owner: %w", err))
+		}
+	}
+	if err := os.Chmod(path, md.mode); err != nil {
+		errs = append(errs, fmt.Errorf("permissions: %w", err))
+	}
+	for name, value := range md.xattrs {
+		if err := unix.Setxattr(path, name, value, 0); err != nil {
+			if errors.Is(err, unix.ENOTSUP) {
+				// Typical for SMB and FAT destinations; one warning is enough.
+				errs = append(errs, fmt.Errorf("extended attributes: not supported by %s", path))
+				break
+			}
+			errs = append(errs, fmt.Errorf("extended attribute %s: %w", name, err))
+		}
+	}
+
+	return errs
+}
+
+// listXattrs returns the names of path's extended attributes.
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = unix.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	size, err := unix.Getxattr(path, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	size, err = unix.Getxattr(path, name, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package maintenance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestMetadata_OwnerAndXattrsAsRoot(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("ownership and extended attributes are only preserved as root")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dst := filepath.Join(dir, "dst.txt")
+	mustWriteFile(t, src, "alpha")
+	mustWriteFile(t, dst, "alpha")
+
+	if err := unix.Setxattr(src, "user.origin", []byte("scanner-3"), 0); err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			t.Skip("filesystem does not support extended attributes")
+		}
+		t.Fatalf("setxattr: %v", err)
+	}
+	if err := os.Chown(src, 1234, 5678); err != nil {
+		t.Fatalf("chown: %v", err)
+	}
+
+	md, err := readMetadata(src)
+	if err != nil {
+		t.Fatalf("read metadata: %v", err)
+	}
+	if errs := applyMetadata(dst, md); len(errs) != 0 {
+		t.Fatalf("apply metadata: %v", errs)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	st := info.Sys().(*syscall.Stat_t)
+	if st.Uid != 1234 || st.Gid != 5678 {
+		t.Fatalf("owner %d:%d, want 1234:5678", st.Uid, st.Gid)
+	}
+	value, err := getXattr(dst, "user.origin")
+	if err != nil || string(value) != "scanner-3" {
+		t.Fatalf("xattr user.origin = %q, %v", value, err)
+	}
+}
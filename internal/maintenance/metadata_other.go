@@ -0,0 +1,19 @@
+//go:build !linux && !windows
+
+package maintenance
+
+import (
+	"fmt"
+	"os"
+)
+
+// readPlatformMetadata adds nothing beyond times and permission bits here.
+func readPlatformMetadata(path string, info os.FileInfo, md *fileMetadata) {}
+
+// applyPlatformMetadata sets the permission bits.
+func applyPlatformMetadata(path string, md fileMetadata) []error {
+	if err := os.Chmod(path, md.mode); err != nil {
+		return []error{fmt.Errorf("permissions: %w", err)}
+	}
+	return nil
+}
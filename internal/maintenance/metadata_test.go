@@ -0,0 +1,136 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestWorker_Integration_PreservesMetadata(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	p := filepath.Join(src, "sub", "ledger.csv")
+	mustMkdirAll(t, filepath.Dir(p))
+	mustWriteFile(t, p, "a,b\n")
+	if err := os.Chmod(p, 0o640); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	mtime := time.Now().AddDate(0, 0, -10).Truncate(time.Second)
+	atime := time.Now().AddDate(0, 0, -7).Truncate(time.Second)
+	if err := os.Chtimes(p, atime, mtime); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	// assertMetadata checks what every platform preserves.
+	assertMetadata := func(t *testing.T, path string) {
+		t.Helper()
+		md, err := readMetadata(path)
+		if err != nil {
+			t.Fatalf("read metadata: %v", err)
+		}
+		if !md.modTime.Equal(mtime) {
+			t.Fatalf("%s: modification time %s, want %s", path, md.modTime, mtime)
+		}
+		if !md.accessTime.Equal(atime) && !md.accessTime.Equal(mtime) {
+			t.Fatalf("%s: access time %s, want %s", path, md.accessTime, atime)
+		}
+		if runtime.GOOS != "windows" && md.mode != 0o640 {
+			t.Fatalf("%s: mode %o, want 640", path, md.mode)
+		}
+	}
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	assertNotExists(t, p)
+
+	today := time.Now().Format(backupDateLayout)
+	stored := filepath.Join(backup, today, filepath.Base(src), "sub", "ledger.csv")
+	assertMetadata(t, stored)
+	storedMd, err := readMetadata(stored)
+	if err != nil {
+		t.Fatalf("read backup metadata: %v", err)
+	}
+
+	if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	assertMetadata(t, p)
+
+	// The restored access time is the backup's, not the modification time
+	// from the manifest.
+	md, err := readMetadata(p)
+	if err != nil {
+		t.Fatalf("read restored metadata: %v", err)
+	}
+	if !md.accessTime.Equal(storedMd.accessTime) {
+		t.Fatalf("restored access time %s, want %s", md.accessTime, storedMd.accessTime)
+	}
+}
+
+func TestApplyMetadata_ReportsEachFailure(t *testing.T) {
+	md := fileMetadata{modTime: time.Now(), accessTime: time.Now(), mode: 0o644}
+	errs := applyMetadata(filepath.Join(t.TempDir(), "missing"), md)
+	if len(errs) == 0 {
+		t.Fatalf("expected errors for a missing file")
+	}
+}
+
+func TestWorker_Integration_UnreadableSourceGetsReadableBackup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not copied on Windows")
+	}
+	if os.Geteuid() != 0 {
+		t.Skip("only root can back up a file its owner cannot read")
+	}
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Move = false // copy and verify, which reads the backup
+
+	p := filepath.Join(src, "drop.bin")
+	mustWriteFile(t, p, "write only")
+	mustSetAgeDays(t, p, 10)
+	if err := os.Chmod(p, 0o200); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	assertNotExists(t, p)
+
+	today := time.Now().Format(backupDateLayout)
+	stored := filepath.Join(backup, today, filepath.Base(src), "drop.bin")
+	info, err := os.Stat(stored)
+	if err != nil {
+		t.Fatalf("stat backup: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0o600 {
+		t.Fatalf("backup mode %o, want 600", got)
+	}
+	records, _, err := ReadBackupManifest(filepath.Join(backup, today, backupManifestName))
+	if err != nil || len(records) != 1 || records[0].SourceMode != "0200" {
+		t.Fatalf("expected one record with source mode 0200, got %+v (err=%v)", records, err)
+	}
+
+	if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	info, err = os.Stat(p)
+	if err != nil {
+		t.Fatalf("stat restored file: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0o200 {
+		t.Fatalf("restored mode %o, want 200", got)
+	}
+}
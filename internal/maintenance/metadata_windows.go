@@ -0,0 +1,15 @@
+//go:build windows
+
+package maintenance
+
+import "os"
+
+// readPlatformMetadata adds nothing on Windows.
+func readPlatformMetadata(path string, info os.FileInfo, md *fileMetadata) {}
+
+// applyPlatformMetadata sets nothing on Windows. Permission bits only map to
+// the read-only attribute there, and a read-only backup could no longer be
+// removed by retention or replaced by a restore.
+func applyPlatformMetadata(path string, md fileMetadata) []error {
+	return nil
+}
@@ -0,0 +1,115 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestSameDevice(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		t.Skip("filesystems cannot be told apart on this platform")
+	}
+	dir := t.TempDir()
+	mustMkdirAll(t, filepath.Join(dir, "a"))
+	mustMkdirAll(t, filepath.Join(dir, "b"))
+
+	same, err := sameDevice(filepath.Join(dir, "a"), filepath.Join(dir, "b"))
+	if err != nil || !same {
+		t.Fatalf("sameDevice on one temp dir = %v, %v; want true", same, err)
+	}
+	if _, err := sameDevice(filepath.Join(dir, "missing"), dir); err == nil {
+		t.Fatalf("expected error for a missing path")
+	}
+}
+
+func TestWorker_Integration_MovesOnSameFilesystem(t *testing.T) {
+	tests := []struct {
+		name      string
+		move      bool
+		compress  types.Compression
+		wantMoved bool
+	}{
+		{name: "move", move: true, wantMoved: true},
+		{name: "move disabled", move: false, wantMoved: false},
+		{name: "compressed backups are copied", move: true, compress: types.CompressGzip, wantMoved: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, src, backup := newSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.Backup = types.DefaultBackupOptions()
+			cfg.Backup.Move = tt.move
+			cfg.Backup.Compress = tt.compress
+
+			p := filepath.Join(src, "sub", "scan.pdf")
+			mustMkdirAll(t, filepath.Dir(p))
+			mustWriteFile(t, p, "scan")
+			mustSetAgeDays(t, p, 10)
+			before, err := os.Stat(p)
+			if err != nil {
+				t.Fatalf("stat: %v", err)
+			}
+
+			pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+			if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+			assertNotExists(t, p)
+
+			today := time.Now().Format(backupDateLayout)
+			records, _, err := ReadBackupManifest(filepath.Join(backup, today, backupManifestName))
+			if err != nil || len(records) != 1 {
+				t.Fatalf("read manifest: records=%d err=%v", len(records), err)
+			}
+			rec := records[0]
+			if rec.Moved != tt.wantMoved || (rec.Hash == "") != tt.wantMoved {
+				t.Fatalf("unexpected manifest record: %+v", rec)
+			}
+
+			if tt.wantMoved {
+				// A rename keeps the same file.
+				after, err := os.Stat(rec.BackupPath)
+				if err != nil || !os.SameFile(before, after) {
+					t.Fatalf("backup is not the renamed source: %v", err)
+				}
+			}
+
+			summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+			if err != nil || summary.Restored != 1 {
+				t.Fatalf("restore: summary=%+v err=%v", summary, err)
+			}
+			assertFileContents(t, p, "scan")
+		})
+	}
+}
+
+func TestWorker_Integration_MoveFallsBackToCopyOnNameCollision(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+
+	p := filepath.Join(src, "report.pdf")
+	mustWriteFile(t, p, "new report")
+	mustSetAgeDays(t, p, 10)
+
+	today := time.Now().Format(backupDateLayout)
+	existing := filepath.Join(backup, today, filepath.Base(src), "report.pdf")
+	mustMkdirAll(t, filepath.Dir(existing))
+	mustWriteFile(t, existing, "old report")
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	assertNotExists(t, p)
+	assertFileContents(t, existing, "old report")
+	assertFileContents(t, versionedPath(existing, 2), "new report")
+}
@@ -0,0 +1,160 @@
+package maintenance
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"file-maintenance/internal/logging"
+	"file-maintenance/internal/types"
+)
+
+// objectsDirName is the folder under a backup root that holds the
+// content-addressed store ([backup] layout=objects).
+const objectsDirName = "objects"
+
+// objectRelPath returns where content with checksum sum is stored in format f,
+// relative to the backup root and slash-separated, e.g.
+// "objects/9b/9b74c9897bac770f...". The two-character fan-out keeps folders
+// small when there are many objects.
+func objectRelPath(sum string, f storedFormat) string {
+	return storedName(path.Join(objectsDirName, sum[:2], sum), f)
+}
+
+// objectPruneGrace is how long an object is kept after it was last written or
+// reused, whether or not a manifest refers to it. A run writes or reuses an
+// object before it appends the record that refers to it, so a retention pass
+// running at the same time would otherwise see an unreferenced object and
+// delete it from under the record.
+const objectPruneGrace = 24 * time.Hour
+
+// resolveObjectTarget decides where the backup of srcPath goes in the objects
+// layout: the object named by the source's checksum.
+//
+// The source is hashed first, so identical content already in the store is
+// found without copying anything. An existing object is trusted by its name:
+// objects are only ever renamed into place after a complete (and, with verify,
+// checked) copy. Its modification time is set to now, best effort, which
+// protects it from pruning until the new record is written (see
+// objectPruneGrace). The source is hashed through ctl (see hashStored).
+func resolveObjectTarget(srcPath, backupRoot string, opts types.BackupOptions, ctl copyControl) (backupTarget, error) {
+	sum, err := hashStored(srcPath, opts.Hash, storedFormat{}, ctl)
+	if err != nil {
+		return backupTarget{}, err
+	}
+
+	p := filepath.Join(backupRoot, filepath.FromSlash(objectRelPath(sum, formatOf(opts))))
+	info, err := os.Stat(p)
+	switch {
+	case err == nil && info.Mode().IsRegular():
+		now := time.Now()
+		_ = os.Chtimes(p, now, now)
+		return backupTarget{path: p, reused: true, sum: sum}, nil
+	case err == nil:
+		return backupTarget{}, fmt.Errorf("object path is not a regular file: %s", p)
+	case !os.IsNotExist(err):
+		return backupTarget{}, err
+	}
+
+	return backupTarget{path: p, sum: sum}, nil
+}
+
+// pruneUnreferencedObjects deletes objects under backupRoot that no dated
+// folder's manifest refers to any more, and returns how many were removed.
+//
+// Reference counts are rebuilt from the manifests on every call instead of
+// being stored, so they cannot drift from the records restore relies on. An
+// object is kept while at least one remaining record points to it.
+//
+// Safety:
+//   - If any manifest cannot be read, or has lines that do not parse, nothing is
+//     removed: a damaged line might be the only reference to an object.
+//   - Partial copies (".<name>.partial") and leftover ".tmp" files are not
+//     objects and are left alone; they may belong to a copy that is still
+//     running.
+//   - Objects written or reused within objectPruneGrace before the prune
+//     started are kept: their record may not have been written yet.
+func pruneUnreferencedObjects(backupRoot string, log *logging.Logger) (int, error) {
+	started := time.Now()
+
+	objectsDir := filepath.Join(backupRoot, objectsDirName)
+	if _, err := os.Stat(objectsDir); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(backupRoot)
+	if err != nil {
+		return 0, fmt.Errorf("read backup root: %w", err)
+	}
+
+	refs := make(map[string]int)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, ok := parseBackupDateFolder(e.Name()); !ok {
+			continue
+		}
+
+		manifestPath := filepath.Join(backupRoot, e.Name(), backupManifestName)
+		records, skipped, err := ReadBackupManifest(manifestPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, fmt.Errorf("not pruning objects: %w", err)
+		}
+		if skipped > 0 {
+			log.Warnf("Not pruning backup objects: %d unreadable line(s) in %s", skipped, manifestPath)
+			return 0, nil
+		}
+		for _, rec := range records {
+			if rec.Object != "" {
+				refs[path.Clean(rec.Object)]++
+			}
+		}
+	}
+
+	removed := 0
+	fanOut, err := os.ReadDir(objectsDir)
+	if err != nil {
+		return 0, fmt.Errorf("read objects folder: %w", err)
+	}
+	for _, dir := range fanOut {
+		if !dir.IsDir() {
+			continue
+		}
+		full := filepath.Join(objectsDir, dir.Name())
+		objects, err := os.ReadDir(full)
+		if err != nil {
+			log.Errorf("Could not read objects folder %s: %v", full, err)
+			continue
+		}
+		for _, obj := range objects {
+			if !obj.Type().IsRegular() || strings.HasSuffix(obj.Name(), ".tmp") || isPartialName(obj.Name()) {
+				continue
+			}
+			rel := path.Join(objectsDirName, dir.Name(), obj.Name())
+			if refs[rel] > 0 {
+				continue
+			}
+			info, err := obj.Info()
+			if err != nil || started.Sub(info.ModTime()) < objectPruneGrace {
+				continue
+			}
+			if err := os.Remove(filepath.Join(full, obj.Name())); err != nil {
+				log.Errorf("Could not remove unreferenced backup object %s: %v", rel, err)
+				continue
+			}
+			log.Debugf("Removed unreferenced backup object: %s", rel)
+			removed++
+		}
+		// Drop the fan-out folder once it is empty; fails harmlessly otherwise.
+		_ = os.Remove(full)
+	}
+
+	return removed, nil
+}
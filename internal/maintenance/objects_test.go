@@ -0,0 +1,165 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// listObjects returns the slash-separated paths of all objects under backupRoot.
+func listObjects(t *testing.T, backupRoot string) []string {
+	t.Helper()
+	var out []string
+	_ = filepath.WalkDir(filepath.Join(backupRoot, objectsDirName), func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(backupRoot, p)
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	return out
+}
+
+func TestWorker_Integration_ObjectsLayoutDeduplicates(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Layout = types.LayoutObjects
+
+	reports := filepath.Join(root, "reports")
+	mustMkdirAll(t, reports)
+
+	files := map[string]string{
+		filepath.Join(src, "a.pdf"):         "same report",
+		filepath.Join(src, "sub", "b.pdf"):  "same report",
+		filepath.Join(reports, "c.pdf"):     "same report",
+		filepath.Join(reports, "other.pdf"): "different report",
+	}
+	for p, contents := range files {
+		mustMkdirAll(t, filepath.Dir(p))
+		mustWriteFile(t, p, contents)
+		mustSetAgeDays(t, p, 10)
+	}
+
+	pathconfig := []types.PathConfig{
+		{Path: src, Backup: true, IsDir: true},
+		{Path: reports, Backup: true, IsDir: true},
+	}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	for p := range files {
+		assertNotExists(t, p)
+	}
+
+	if objects := listObjects(t, backup); len(objects) != 2 {
+		t.Fatalf("expected 2 objects for 2 distinct contents, got %v", objects)
+	}
+
+	// The dated folder holds only the index.
+	today := time.Now().Format(backupDateLayout)
+	assertNotExists(t, filepath.Join(backup, today, filepath.Base(src), "a.pdf"))
+	records, _, err := ReadBackupManifest(filepath.Join(backup, today, backupManifestName))
+	if err != nil || len(records) != len(files) {
+		t.Fatalf("read index: records=%d err=%v", len(records), err)
+	}
+	for _, rec := range records {
+		if rec.Object == "" {
+			t.Fatalf("record without object: %+v", rec)
+		}
+		assertExists(t, filepath.Join(backup, filepath.FromSlash(rec.Object)))
+	}
+
+	// Restore finds every file through the index.
+	summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+	if err != nil || summary.Restored != len(files) || summary.Failed != 0 {
+		t.Fatalf("restore: summary=%+v err=%v", summary, err)
+	}
+	for p, contents := range files {
+		assertFileContents(t, p, contents)
+	}
+
+	target := filepath.Join(root, "restored")
+	if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today, Target: target, Match: "**/sub/**"}, log); err != nil {
+		t.Fatalf("restore to target: %v", err)
+	}
+	assertFileContents(t, filepath.Join(target, filepath.Base(src), "sub", "b.pdf"), "same report")
+}
+
+func TestRemoveOldBackups_PrunesUnreferencedObjects(t *testing.T) {
+	_, log := newTestCfgAndLogger(t, t.TempDir())
+	backup := t.TempDir()
+
+	oldDay := time.Now().AddDate(0, 0, -40).Format(backupDateLayout)
+	today := time.Now().Format(backupDateLayout)
+
+	writeIndex := func(day string, objects ...string) {
+		t.Helper()
+		m := newBackupManifest(backup, "run-"+day)
+		for i, obj := range objects {
+			rec := BackupRecord{
+				BackupPath: filepath.Join(backup, day, "docs", string(rune('a'+i))+".pdf"),
+				Object:     obj,
+			}
+			if err := m.append(rec); err != nil {
+				t.Fatalf("append record: %v", err)
+			}
+		}
+		if err := m.close(); err != nil {
+			t.Fatalf("close manifest: %v", err)
+		}
+	}
+
+	mustMkdirAll(t, filepath.Join(backup, oldDay))
+	mustMkdirAll(t, filepath.Join(backup, today))
+	onlyOld := "objects/aa/aa11"
+	shared := "objects/bb/bb22"
+	// justWritten has no record yet, as while a concurrent run is between
+	// writing an object and appending its record.
+	justWritten := "objects/dd/dd44"
+	for _, obj := range []string{onlyOld, shared, "objects/cc/cc33.tmp", justWritten} {
+		p := filepath.Join(backup, filepath.FromSlash(obj))
+		mustMkdirAll(t, filepath.Dir(p))
+		mustWriteFile(t, p, obj)
+		if obj != justWritten {
+			mustSetAgeDays(t, p, 40)
+		}
+	}
+	writeIndex(oldDay, onlyOld, shared)
+	writeIndex(today, shared)
+
+	removed, err := RemoveOldBackups(backup, 30, log)
+	if err != nil || removed != 1 {
+		t.Fatalf("RemoveOldBackups: removed=%d err=%v", removed, err)
+	}
+
+	assertNotExists(t, filepath.Join(backup, filepath.FromSlash(onlyOld)))
+	assertNotExists(t, filepath.Join(backup, "objects", "aa"))
+	assertExists(t, filepath.Join(backup, filepath.FromSlash(shared)))
+	assertExists(t, filepath.Join(backup, "objects", "cc", "cc33.tmp"))
+	assertExists(t, filepath.Join(backup, filepath.FromSlash(justWritten)))
+}
+
+func TestPruneUnreferencedObjects_KeepsAllOnDamagedIndex(t *testing.T) {
+	_, log := newTestCfgAndLogger(t, t.TempDir())
+	backup := t.TempDir()
+
+	today := time.Now().Format(backupDateLayout)
+	mustMkdirAll(t, filepath.Join(backup, today))
+	mustWriteFile(t, filepath.Join(backup, today, backupManifestName), "{\"object\":\"objects/aa/aa1\"}\n{truncated")
+
+	obj := filepath.Join(backup, "objects", "bb", "bb2")
+	mustMkdirAll(t, filepath.Dir(obj))
+	mustWriteFile(t, obj, "unreferenced, but the index is damaged")
+
+	removed, err := pruneUnreferencedObjects(backup, log)
+	if err != nil || removed != 0 {
+		t.Fatalf("prune: removed=%d err=%v", removed, err)
+	}
+	assertExists(t, obj)
+}
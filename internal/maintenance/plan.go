@@ -0,0 +1,143 @@
+package maintenance
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlanEntry describes one candidate file selected by plan mode.
+//
+// Each entry records exactly what a real -run would do with the file:
+// - SourcePath is deleted.
+// - BackupPath is where the copy would be written (empty when backup is disabled).
+// - ConfigPath is the [paths] entry that selected the file.
+type PlanEntry struct {
+	SourcePath string    `json:"source_path"`
+	BackupPath string    `json:"backup_path,omitempty"`
+	SizeBytes  uint64    `json:"size_bytes"`
+	ModTime    time.Time `json:"mod_time"`
+	ConfigPath string    `json:"config_path"`
+	FolderRoot string    `json:"folder_root"`
+	Backup     bool      `json:"backup"`
+}
+
+// PlanManifest is the reviewable output of plan mode.
+//
+// It is written as JSON (machine-readable, used to execute a reviewed plan) and
+// as CSV (easy to open in a spreadsheet during review).
+type PlanManifest struct {
+	CreatedAt   time.Time   `json:"created_at"`
+	BackupRoot  string      `json:"backup_root"`
+	Days        int         `json:"days"`
+	TotalBytes  uint64      `json:"total_bytes"`
+	BackupBytes uint64      `json:"backup_bytes"`
+	Files       []PlanEntry `json:"files"`
+}
+
+// ReadPlanManifest loads a plan previously written by plan mode.
+//
+// Either the JSON file or the plan base name can be given; the CSV file is for
+// review only and is never read back.
+func ReadPlanManifest(planFile string) (PlanManifest, error) {
+	jsonPath, _ := planOutputPaths(planFile)
+
+	b, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return PlanManifest{}, fmt.Errorf("read plan: %w", err)
+	}
+
+	var manifest PlanManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return PlanManifest{}, fmt.Errorf("parse plan %s: %w", jsonPath, err)
+	}
+
+	return manifest, nil
+}
+
+// planOutputPaths returns the JSON and CSV file names for a plan path.
+//
+// Any extension on planFile is replaced, so both "-plan share" and
+// "-plan share.json" produce share.json and share.csv.
+func planOutputPaths(planFile string) (jsonPath, csvPath string) {
+	base := strings.TrimSuffix(planFile, filepath.Ext(planFile))
+	return base + ".json", base + ".csv"
+}
+
+// writePlanManifest writes the manifest as JSON and CSV.
+//
+// Both files are written to a temporary name first and renamed into place, so a
+// failed write never leaves a truncated plan that could later be executed.
+func writePlanManifest(planFile string, manifest PlanManifest) error {
+	jsonPath, csvPath := planOutputPaths(planFile)
+
+	if err := os.MkdirAll(filepath.Dir(jsonPath), 0o755); err != nil {
+		return fmt.Errorf("create plan directory: %w", err)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode plan: %w", err)
+	}
+	if err := writeFileAtomic(jsonPath, func(f *os.File) error {
+		_, err := f.Write(append(b, '\n'))
+		return err
+	}); err != nil {
+		return fmt.Errorf("write plan json: %w", err)
+	}
+
+	if err := writeFileAtomic(csvPath, func(f *os.File) error {
+		w := csv.NewWriter(f)
+		if err := w.Write([]string{"source_path", "backup_path", "size_bytes", "mod_time", "config_path", "backup"}); err != nil {
+			return err
+		}
+		for _, e := range manifest.Files {
+			record := []string{
+				e.SourcePath,
+				e.BackupPath,
+				strconv.FormatUint(e.SizeBytes, 10),
+				e.ModTime.Format(time.RFC3339Nano),
+				e.ConfigPath,
+				strconv.FormatBool(e.Backup),
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}); err != nil {
+		return fmt.Errorf("write plan csv: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes path through a temporary file in the same directory
+// and renames it into place once write returns successfully.
+func writeFileAtomic(path string, write func(f *os.File) error) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	// Close before rename (Windows requires the handle to be closed).
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
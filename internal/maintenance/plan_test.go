@@ -0,0 +1,263 @@
+package maintenance
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestPlanOutputPaths_Table(t *testing.T) {
+	tests := []struct {
+		name     string
+		planFile string
+		wantJSON string
+		wantCSV  string
+	}{
+		{"no extension", filepath.Join("plans", "share"), filepath.Join("plans", "share.json"), filepath.Join("plans", "share.csv")},
+		{"json extension", filepath.Join("plans", "share.json"), filepath.Join("plans", "share.json"), filepath.Join("plans", "share.csv")},
+		{"csv extension", filepath.Join("plans", "share.csv"), filepath.Join("plans", "share.json"), filepath.Join("plans", "share.csv")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotJSON, gotCSV := planOutputPaths(tt.planFile)
+			if gotJSON != tt.wantJSON || gotCSV != tt.wantCSV {
+				t.Fatalf("want (%q, %q), got (%q, %q)", tt.wantJSON, tt.wantCSV, gotJSON, gotCSV)
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_PlanModeWritesManifestWithoutTouchingFiles(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+
+	cfg.Days = 5
+	cfg.PlanFile = filepath.Join(root, "plans", "first-run")
+
+	oldPath := filepath.Join(src, "sub", "old.txt")
+	newPath := filepath.Join(src, "new.txt")
+	mustMkdirAll(t, filepath.Dir(oldPath))
+	mustWriteFile(t, oldPath, "old data")
+	mustWriteFile(t, newPath, "new")
+	mustSetAgeDays(t, oldPath, 10)
+	mustSetAgeDays(t, newPath, 1)
+
+	pathconfig := []types.PathConfig{
+		{Path: src, Backup: true, IsDir: true},
+	}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	// Plan mode must never copy or delete.
+	assertExists(t, oldPath)
+	assertExists(t, newPath)
+	if got := countBackupsWithBase(t, backup, "old.txt"); got != 0 {
+		t.Fatalf("expected no backups in plan mode, got %d", got)
+	}
+
+	b, err := os.ReadFile(cfg.PlanFile + ".json")
+	if err != nil {
+		t.Fatalf("read plan json: %v", err)
+	}
+	var manifest PlanManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("decode plan json: %v", err)
+	}
+
+	if len(manifest.Files) != 1 {
+		t.Fatalf("expected 1 planned file, got %d", len(manifest.Files))
+	}
+	entry := manifest.Files[0]
+	if entry.SourcePath != oldPath {
+		t.Fatalf("want source %q, got %q", oldPath, entry.SourcePath)
+	}
+	if entry.ConfigPath != src {
+		t.Fatalf("want config path %q, got %q", src, entry.ConfigPath)
+	}
+	if entry.SizeBytes != uint64(len("old data")) || manifest.BackupBytes != entry.SizeBytes {
+		t.Fatalf("unexpected sizes: entry=%d backupBytes=%d", entry.SizeBytes, manifest.BackupBytes)
+	}
+	wantBackup := filepath.Join(backup, time.Now().Format("02Jan06"), filepath.Base(src), "sub", "old.txt")
+	if entry.BackupPath != wantBackup {
+		t.Fatalf("want backup path %q, got %q", wantBackup, entry.BackupPath)
+	}
+
+	f, err := os.Open(cfg.PlanFile + ".csv")
+	if err != nil {
+		t.Fatalf("open plan csv: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("read plan csv: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header + 1 record, got %d rows", len(records))
+	}
+	if records[1][0] != oldPath {
+		t.Fatalf("want csv source %q, got %q", oldPath, records[1][0])
+	}
+}
+
+func TestApplyPlan_Integration_SkipsFilesChangedSincePlan(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+
+	cfg.Days = 5
+	cfg.PlanFile = filepath.Join(root, "plans", "nightly")
+
+	unchanged := filepath.Join(src, "unchanged.txt")
+	modified := filepath.Join(src, "modified.txt")
+	lateArrival := filepath.Join(src, "late.txt")
+	mustWriteFile(t, unchanged, "same")
+	mustWriteFile(t, modified, "before")
+	mustSetAgeDays(t, unchanged, 10)
+	mustSetAgeDays(t, modified, 10)
+
+	pathconfig := []types.PathConfig{
+		{Path: src, Backup: true, IsDir: true},
+	}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("plan worker error: %v", err)
+	}
+
+	// Changes after the plan was reviewed:
+	// - modified.txt grows (size no longer matches)
+	// - late.txt ages in overnight but is not part of the plan
+	mustWriteFile(t, modified, "after the plan")
+	mustSetAgeDays(t, modified, 10)
+	mustWriteFile(t, lateArrival, "late")
+	mustSetAgeDays(t, lateArrival, 10)
+
+	manifest, err := ReadPlanManifest(cfg.PlanFile + ".json")
+	if err != nil {
+		t.Fatalf("read plan: %v", err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected 2 planned files, got %d", len(manifest.Files))
+	}
+
+	// An entry outside the configured paths must never be processed.
+	outside := filepath.Join(root, "outside.txt")
+	mustWriteFile(t, outside, "x")
+	mustSetAgeDays(t, outside, 10)
+	fi, err := os.Stat(outside)
+	if err != nil {
+		t.Fatalf("stat outside: %v", err)
+	}
+	manifest.Files = append(manifest.Files, PlanEntry{
+		SourcePath: outside,
+		SizeBytes:  uint64(fi.Size()),
+		ModTime:    fi.ModTime(),
+		ConfigPath: root,
+		FolderRoot: root,
+	})
+
+	cfg.PlanFile = ""
+	if err := ApplyPlan(pathconfig, manifest, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("apply plan error: %v", err)
+	}
+
+	assertNotExists(t, unchanged)
+	if got := countBackupsWithBase(t, backup, "unchanged.txt"); got != 1 {
+		t.Fatalf("expected unchanged.txt backed up once, got %d", got)
+	}
+
+	assertExists(t, modified)
+	assertExists(t, lateArrival)
+	assertExists(t, outside)
+	if got := countBackupsWithBase(t, backup, "modified.txt"); got != 0 {
+		t.Fatalf("expected modified.txt NOT backed up, got %d", got)
+	}
+}
+
+func TestApplyPlan_Integration_RejectsTamperedPlan(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	inside := filepath.Join(src, "inside.txt")
+	outside := filepath.Join(root, "outside.txt")
+	for _, p := range []string{inside, outside} {
+		mustWriteFile(t, p, "data")
+		mustSetAgeDays(t, p, 10)
+	}
+	entry := func(p, folderRoot string, backup bool) PlanEntry {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		return PlanEntry{
+			SourcePath: p,
+			SizeBytes:  uint64(fi.Size()),
+			ModTime:    fi.ModTime(),
+			ConfigPath: src,
+			FolderRoot: folderRoot,
+			Backup:     backup,
+		}
+	}
+
+	manifest := PlanManifest{
+		CreatedAt: time.Now(),
+		Files: []PlanEntry{
+			// Claims a configured path but points outside it.
+			entry(outside, root, true),
+			// Inside the path, with a folder root that would write the backup
+			// outside the backup root, and backup turned off in the plan.
+			entry(inside, filepath.Join(src, "..", "..", ".."), false),
+		},
+	}
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := ApplyPlan(pathconfig, manifest, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("apply plan error: %v", err)
+	}
+
+	assertExists(t, outside)
+	assertNotExists(t, inside)
+
+	// The configured path requires a backup, and it lands in the usual place.
+	today := time.Now().Format(backupDateLayout)
+	assertFileContents(t, filepath.Join(backup, today, filepath.Base(src), "inside.txt"), "data")
+	if got := countBackupsWithBase(t, backup, "outside.txt"); got != 0 {
+		t.Fatalf("expected outside.txt NOT backed up, got %d", got)
+	}
+}
+
+func TestPlannedFolderRoot_Table(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "scans")
+	file := filepath.Join(dir, "report.pdf")
+
+	tests := []struct {
+		name     string
+		pc       types.PathConfig
+		src      string
+		wantRoot string
+		wantOK   bool
+	}{
+		{name: "inside folder", pc: types.PathConfig{Path: dir, IsDir: true}, src: filepath.Join(dir, "a", "b.txt"), wantRoot: dir, wantOK: true},
+		{name: "folder itself", pc: types.PathConfig{Path: dir, IsDir: true}, src: dir, wantOK: false},
+		{name: "escapes folder", pc: types.PathConfig{Path: dir, IsDir: true}, src: filepath.Join(dir, "..", "other.txt"), wantOK: false},
+		{name: "sibling with same prefix", pc: types.PathConfig{Path: dir, IsDir: true}, src: dir + "-old" + string(filepath.Separator) + "x.txt", wantOK: false},
+		{name: "single file", pc: types.PathConfig{Path: file}, src: file, wantRoot: dir, wantOK: true},
+		{name: "other file for single-file entry", pc: types.PathConfig{Path: file}, src: filepath.Join(dir, "other.pdf"), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, ok := plannedFolderRoot(tt.pc, tt.src)
+			if ok != tt.wantOK || root != tt.wantRoot {
+				t.Fatalf("got (%q, %v), want (%q, %v)", root, ok, tt.wantRoot, tt.wantOK)
+			}
+		})
+	}
+}
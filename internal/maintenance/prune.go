@@ -0,0 +1,153 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"file-maintenance/internal/logging"
+)
+
+// defaultPruneMinAge is used when a path enables prune-empty-dirs without
+// setting prune-min-age.
+const defaultPruneMinAge = 24 * time.Hour
+
+// dirTracker collects what the empty-directory pruning phase needs.
+//
+// Why modification times are captured up front:
+//   - Deleting a file updates its parent directory's modification time, so a
+//     directory emptied during this run always looks brand new afterwards.
+//   - Recording each directory's time before any deletions lets the minimum
+//     age check answer "was this directory recently created or changed by a
+//     user?" rather than "did we just delete something from it?".
+//
+// Directories without a recorded time are never pruned.
+type dirTracker struct {
+	mu      sync.Mutex
+	modTime map[string]time.Time           // dir -> modification time before deletions
+	emptied map[string]map[string]struct{} // configPath -> dirs that lost a file this run
+}
+
+func newDirTracker() *dirTracker {
+	return &dirTracker{
+		modTime: make(map[string]time.Time),
+		emptied: make(map[string]map[string]struct{}),
+	}
+}
+
+// recordDir stores the pre-deletion modification time of dir. The first
+// recorded value wins.
+func (t *dirTracker) recordDir(dir string, mt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.modTime[dir]; !ok {
+		t.modTime[dir] = mt
+	}
+}
+
+// recordAncestors stats and records every directory from dir up to (but not
+// including) root. Used when files come from a plan instead of a walk.
+func (t *dirTracker) recordAncestors(root, dir string) {
+	for isStrictlyUnder(root, dir) {
+		t.mu.Lock()
+		_, ok := t.modTime[dir]
+		t.mu.Unlock()
+		if !ok {
+			fi, err := os.Stat(dir)
+			if err != nil {
+				return
+			}
+			t.recordDir(dir, fi.ModTime())
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// markEmptied records that a file was deleted from dir for configPath.
+func (t *dirTracker) markEmptied(configPath, dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	set, ok := t.emptied[configPath]
+	if !ok {
+		set = make(map[string]struct{})
+		t.emptied[configPath] = set
+	}
+	set[dir] = struct{}{}
+}
+
+// prune removes directories under root that became empty during this run,
+// deepest first, and returns how many were removed.
+//
+// Safety rules:
+//   - root itself is never removed, and nothing outside root is touched.
+//   - A directory is removed only if it is empty now AND its pre-deletion
+//     modification time is at least minAge old.
+//   - os.Remove only removes empty directories, so a file created between the
+//     emptiness check and the removal makes the removal fail harmlessly.
+//   - When a directory is removed, its parent becomes a candidate too, so whole
+//     empty date trees collapse bottom-up.
+func (t *dirTracker) prune(root, configPath string, minAge time.Duration, log *logging.Logger) int {
+	t.mu.Lock()
+	pending := make(map[string]struct{}, len(t.emptied[configPath]))
+	for dir := range t.emptied[configPath] {
+		pending[dir] = struct{}{}
+	}
+	t.mu.Unlock()
+
+	removed := 0
+	for len(pending) > 0 {
+		// Deepest first, so children are handled before their parents.
+		dirs := make([]string, 0, len(pending))
+		for dir := range pending {
+			dirs = append(dirs, dir)
+		}
+		sort.Slice(dirs, func(i, j int) bool {
+			return strings.Count(dirs[i], string(filepath.Separator)) > strings.Count(dirs[j], string(filepath.Separator))
+		})
+		pending = make(map[string]struct{})
+
+		for _, dir := range dirs {
+			if !isStrictlyUnder(root, dir) {
+				continue
+			}
+
+			t.mu.Lock()
+			mt, ok := t.modTime[dir]
+			t.mu.Unlock()
+			if !ok || time.Since(mt) < minAge {
+				continue
+			}
+
+			empty, err := isDirEmpty(dir)
+			if err != nil || !empty {
+				continue
+			}
+
+			if err := os.Remove(dir); err != nil {
+				log.Warnf("Could not remove empty directory %s: %v", dir, err)
+				continue
+			}
+			log.Successf("Removed empty directory: %s", dir)
+			removed++
+
+			if parent := filepath.Dir(dir); isStrictlyUnder(root, parent) {
+				pending[parent] = struct{}{}
+			}
+		}
+	}
+
+	return removed
+}
+
+// isStrictlyUnder reports whether p is inside root and is not root itself.
+func isStrictlyUnder(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+	rel = filepath.Clean(rel)
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
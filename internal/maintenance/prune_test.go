@@ -0,0 +1,104 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestIsStrictlyUnder_Table(t *testing.T) {
+	root := filepath.Join("data", "scans")
+
+	tests := []struct {
+		name string
+		p    string
+		want bool
+	}{
+		{name: "root itself", p: root, want: false},
+		{name: "child", p: filepath.Join(root, "2026"), want: true},
+		{name: "grandchild", p: filepath.Join(root, "2026", "01"), want: true},
+		{name: "parent", p: "data", want: false},
+		{name: "sibling with shared prefix", p: filepath.Join("data", "scans-old"), want: false},
+		{name: "escape via dotdot", p: filepath.Join(root, "..", "other"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStrictlyUnder(root, tt.p); got != tt.want {
+				t.Fatalf("isStrictlyUnder(%q, %q) = %v, want %v", root, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_PruneEmptyDirs(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	// Old files in every directory; mixed/ also keeps a recent file.
+	for _, rel := range []string{"2026/01/old.txt", "fresh/old.txt", "mixed/old.txt"} {
+		p := filepath.Join(src, filepath.FromSlash(rel))
+		mustMkdirAll(t, filepath.Dir(p))
+		mustWriteFile(t, p, "x")
+		mustSetAgeDays(t, p, 10)
+	}
+	mustWriteFile(t, filepath.Join(src, "mixed", "new.txt"), "x")
+	mustMkdirAll(t, filepath.Join(src, "untouched"))
+
+	// Directory times are set last: creating files updates them.
+	for _, rel := range []string{"2026/01", "2026", "mixed", "untouched"} {
+		mustSetAgeDays(t, filepath.Join(src, filepath.FromSlash(rel)), 10)
+	}
+	mustSetAgeDays(t, src, 10)
+
+	minAge := 48 * time.Hour
+	pathconfig := []types.PathConfig{
+		{Path: src, Backup: false, IsDir: true, PruneEmptyDirs: true, PruneMinAge: &minAge},
+	}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	// Emptied and old enough: removed bottom-up.
+	assertNotExists(t, filepath.Join(src, "2026", "01"))
+	assertNotExists(t, filepath.Join(src, "2026"))
+
+	// Emptied but created/changed too recently: kept.
+	assertNotExists(t, filepath.Join(src, "fresh", "old.txt"))
+	assertExists(t, filepath.Join(src, "fresh"))
+
+	// Still has a file: kept.
+	assertExists(t, filepath.Join(src, "mixed", "new.txt"))
+
+	// Empty, but nothing was deleted from it this run: kept.
+	assertExists(t, filepath.Join(src, "untouched"))
+
+	// The configured root is never removed.
+	assertExists(t, src)
+}
+
+func TestWorker_Integration_PruneEmptyDirsDisabledByDefault(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	dir := filepath.Join(src, "2026", "01")
+	p := filepath.Join(dir, "old.txt")
+	mustMkdirAll(t, dir)
+	mustWriteFile(t, p, "x")
+	mustSetAgeDays(t, p, 10)
+	mustSetAgeDays(t, dir, 10)
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: false, IsDir: true}}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	assertNotExists(t, p)
+	assertExists(t, dir)
+}
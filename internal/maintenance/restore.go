@@ -0,0 +1,635 @@
+package maintenance
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"file-maintenance/internal/logging"
+	"file-maintenance/internal/types"
+)
+
+// backupDateLayout is the time layout of the dated backup folders created by
+// buildBackupPath (e.g. 30Jan26).
+const backupDateLayout = "02Jan06"
+
+// RestoreSummary counts the outcome of a restore. In dry-run mode Restored
+// counts the files that would have been restored.
+type RestoreSummary struct {
+	Restored int
+	Skipped  int
+	Failed   int
+}
+
+// Restore copies files from dated backup folders back to where they came from.
+//
+// Selection:
+//   - opts.Dates picks one dated folder ("30Jan26") or an inclusive range
+//     ("01Jan26..31Jan26"). Folders are processed oldest first.
+//   - opts.Match, when set, keeps only files whose original path matches the glob
+//     (same syntax as [paths] include/exclude).
+//
+// Where each file goes:
+//   - With a manifest.jsonl record for the backup file, its source_path is used.
+//     The record's checksum is also checked before the file is restored.
+//   - Without a record, the original is rebuilt from the backup layout
+//     (<folder-name>/<relative-path>) by finding the configured [paths] entry
+//     whose base name is <folder-name>. If none or several match, the file is
+//     skipped with a warning, because guessing could restore over the wrong tree.
+//   - With opts.Target, files go to <Target>/<folder-name>/<relative-path>
+//     instead, so no original locations are touched.
+//
+// Compressed (compress=gzip) and encrypted (encrypt-key) backups are decoded
+// on the way back when their manifest record says so, and lose their ".gz" and
+// ".enc" suffixes. Encrypted backups need opts.Key, the key they were written
+// with. Without a record nothing tells them apart from files that really end
+// in ".gz" or ".enc", so they are restored as stored.
+//
+// In the objects layout (layout=objects) a dated folder holds only its
+// manifest.jsonl, and each record's object is restored to the record's
+// source_path (or under opts.Target, by its logical backup path).
+//
+// In the archive layout (layout=archive) each recorded member of an archive is
+// extracted to its source_path (or <Target>/<folder-name>/<member>) and checked
+// against its recorded checksum before it replaces anything. An archive without
+// records is treated like any other file.
+//
+// Metadata kept on a backup copy (times, permissions, and on Linux as root
+// ownership and extended attributes, see applyMetadata) is reapplied to the
+// restored file, best effort. Archive members and objects keep no metadata:
+// they only get back their recorded modification time, and their permissions
+// are the defaults for new files.
+//
+// Existing destination files follow opts.Conflict: skip (default), overwrite,
+// or rename to "name (2).ext". Backups are never modified or removed.
+func Restore(pathconfig []types.PathConfig, backupRoot string, opts types.RestoreOptions, log *logging.Logger) (RestoreSummary, error) {
+	var summary RestoreSummary
+
+	from, to, err := parseRestoreDates(opts.Dates)
+	if err != nil {
+		return summary, err
+	}
+	if opts.Conflict == "" {
+		opts.Conflict = types.RestoreSkip
+	}
+
+	dateDirs, err := selectBackupDateFolders(backupRoot, from, to)
+	if err != nil {
+		return summary, err
+	}
+	if len(dateDirs) == 0 {
+		return summary, fmt.Errorf("no backup folders in %s match %s", backupRoot, opts.Dates)
+	}
+
+	if opts.DryRun {
+		log.Info("Restore dry run - nothing will be written")
+	}
+
+	for _, dateDir := range dateDirs {
+		dateFolder := filepath.Base(dateDir)
+		log.Infof("Restoring from backup folder: %s", dateDir)
+
+		records, archived := loadRestoreRecords(dateDir, log)
+
+		// restoreOne filters and restores one backup file. rel is its
+		// <folder-name>/<relative-path> inside the dated folder. member is set
+		// for a file read from an archive, backupPath then only names it.
+		restoreOne := func(backupPath, rel string, record *BackupRecord, member io.Reader) {
+			original := ""
+			if record != nil {
+				original = record.SourcePath
+			} else {
+				var err error
+				original, err = originalFromLayout(rel, pathconfig)
+				if err != nil && opts.Target == "" {
+					log.Warnf("Skipping %s: %v", backupPath, err)
+					summary.Skipped++
+					return
+				}
+			}
+
+			// Match against the original path when known, else the backup layout.
+			matchPath := original
+			if matchPath == "" {
+				matchPath = rel
+			}
+			if opts.Match != "" && !matchRestorePattern(opts.Match, matchPath) {
+				return
+			}
+
+			dest := original
+			if opts.Target != "" {
+				dest = filepath.Join(opts.Target, rel)
+				// Objects are listed under their logical name, which has no
+				// stored suffixes to remove.
+				if record != nil && record.Object == "" {
+					if record.Encryption != "" {
+						dest = strings.TrimSuffix(dest, encSuffix)
+					}
+					if isCompressed(types.Compression(record.Compression)) {
+						dest = strings.TrimSuffix(dest, gzipSuffix)
+					}
+				}
+			}
+
+			var outcome restoreOutcome
+			if member != nil {
+				outcome = restoreMember(member, backupPath, dest, record, opts, log)
+			} else {
+				outcome = restoreFile(backupPath, dest, record, opts, log)
+			}
+			switch outcome {
+			case restoreDone:
+				summary.Restored++
+			case restoreSkipped:
+				summary.Skipped++
+			case restoreFailed:
+				summary.Failed++
+			}
+		}
+
+		err := filepath.WalkDir(dateDir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				log.Errorf("Walk error (%s): %v", path, err)
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if filepath.Dir(path) == dateDir && d.Name() == backupManifestName {
+				return nil
+			}
+			rel, err := filepath.Rel(dateDir, path)
+			if err != nil {
+				return nil
+			}
+			if _, ok := archived[filepath.ToSlash(rel)]; ok {
+				// Extracted member by member below.
+				return nil
+			}
+
+			rec, recorded := records[filepath.ToSlash(rel)]
+			if !recorded && (strings.HasSuffix(d.Name(), ".tmp") || isPartialName(d.Name())) {
+				// Leftover from an interrupted copy or archive; never a
+				// complete backup. A recorded one is the backup of a source
+				// file that happens to have such a name.
+				return nil
+			}
+
+			var record *BackupRecord
+			if recorded && rec.Object == "" {
+				record = &rec
+			}
+
+			restoreOne(path, rel, record, nil)
+			return nil
+		})
+		if err != nil {
+			return summary, fmt.Errorf("walk %s: %w", dateFolder, err)
+		}
+
+		// layout=objects: the dated folder holds only the index, and each
+		// record points to its content in the objects store.
+		var objectRels []string
+		for rel, rec := range records {
+			if rec.Object != "" {
+				objectRels = append(objectRels, rel)
+			}
+		}
+		sort.Strings(objectRels)
+		for _, rel := range objectRels {
+			rec := records[rel]
+			restoreOne(filepath.Join(backupRoot, filepath.FromSlash(rec.Object)), filepath.FromSlash(rel), &rec, nil)
+		}
+
+		// layout=archive: extract the recorded members of each archive.
+		archiveRels := make([]string, 0, len(archived))
+		for rel := range archived {
+			archiveRels = append(archiveRels, rel)
+		}
+		sort.Strings(archiveRels)
+		for _, rel := range archiveRels {
+			archivePath := filepath.Join(dateDir, filepath.FromSlash(rel))
+			members := archived[rel]
+			var format types.ArchiveFormat
+			for _, rec := range members {
+				format = types.ArchiveFormat(rec.Archive)
+				break
+			}
+
+			err := readArchive(archivePath, format, func(name string, r io.Reader) error {
+				rec, ok := members[name]
+				if !ok {
+					return nil
+				}
+				delete(members, name)
+				memberRel := filepath.Join(filepath.Base(rec.FolderRoot), filepath.FromSlash(name))
+				restoreOne(filepath.Join(archivePath, filepath.FromSlash(name)), memberRel, &rec, r)
+				return nil
+			})
+			if err != nil {
+				log.Errorf("Cannot read archive %s: %v", archivePath, err)
+			}
+			missing := make([]string, 0, len(members))
+			for name := range members {
+				missing = append(missing, name)
+			}
+			sort.Strings(missing)
+			for _, name := range missing {
+				log.Errorf("Archive %s does not hold recorded file %s, not restoring", archivePath, name)
+				summary.Failed++
+			}
+		}
+	}
+
+	verb := "restored"
+	if opts.DryRun {
+		verb = "would be restored"
+	}
+	log.Countf("Restore finished: %d file(s) %s, %d skipped, %d failed", summary.Restored, verb, summary.Skipped, summary.Failed)
+
+	return summary, nil
+}
+
+type restoreOutcome int
+
+const (
+	restoreDone restoreOutcome = iota
+	restoreSkipped
+	restoreFailed
+)
+
+// restoreFile restores one backup file to dest, applying the conflict policy.
+func restoreFile(backupPath, dest string, record *BackupRecord, opts types.RestoreOptions, log *logging.Logger) restoreOutcome {
+	dest, outcome, ok := restoreDestination(backupPath, dest, opts, log)
+	if !ok {
+		return outcome
+	}
+
+	// A backup copy carries its source's metadata (see backupTo); read it
+	// before hashing the backup touches its access time. Objects are shared
+	// by many files and carry none.
+	var md *fileMetadata
+	if record == nil || record.Object == "" {
+		if m, err := readMetadata(backupPath); err == nil {
+			md = &m
+		}
+	}
+	// The copy may have been made readable; the record has the exact bits.
+	if md != nil && record != nil && record.SourceMode != "" {
+		if mode, err := parseMode(record.SourceMode); err == nil {
+			md.mode = mode
+		} else {
+			log.Warnf("Ignoring recorded permissions of %s: %v", backupPath, err)
+		}
+	}
+
+	// With a manifest record, refuse to restore a backup whose content no
+	// longer matches the checksum recorded when it was made.
+	copyOpts := types.BackupOptions{Verify: true}
+	stored, err := recordFormat(record, opts.Key)
+	if err != nil {
+		log.Errorf("Cannot restore %s: %v", backupPath, err)
+		return restoreFailed
+	}
+	if record != nil && record.Hash != "" {
+		copyOpts.Hash = types.HashAlgorithm(record.HashAlgorithm)
+		sum, err := hashStored(backupPath, copyOpts.Hash, stored, copyControl{})
+		if err != nil {
+			log.Errorf("Cannot read backup %s: %v", backupPath, err)
+			return restoreFailed
+		}
+		if sum != record.Hash {
+			log.Errorf("Backup %s does not match its manifest checksum, not restoring", backupPath)
+			return restoreFailed
+		}
+	}
+
+	if _, err := streamCopy(backupPath, dest, stored, copyOpts, copyControl{}); err != nil {
+		log.Errorf("Restore failed for %s -> %s: %v", backupPath, dest, err)
+		return restoreFailed
+	}
+	if md != nil {
+		for _, err := range applyMetadata(dest, *md) {
+			log.Warnf("Could not restore %v on %s", err, dest)
+		}
+	}
+
+	finishRestore(backupPath, dest, record, md == nil, log)
+	return restoreDone
+}
+
+// restoreMember restores one file read from an archive to dest, applying the
+// conflict policy. backupPath names the member in log messages.
+//
+// An archive is read once, front to back, so the member is written to a
+// temporary file and checked against its recorded checksum before it is
+// renamed over anything.
+//
+// Archives keep only each member's modification time (see archiveWriter), so
+// that is all that is restored; the file gets the default permissions.
+func restoreMember(r io.Reader, backupPath, dest string, record *BackupRecord, opts types.RestoreOptions, log *logging.Logger) restoreOutcome {
+	dest, outcome, ok := restoreDestination(backupPath, dest, opts, log)
+	if !ok {
+		return outcome
+	}
+
+	if err := writeRestored(r, dest, record); err != nil {
+		log.Errorf("Restore failed for %s -> %s: %v", backupPath, dest, err)
+		return restoreFailed
+	}
+
+	finishRestore(backupPath, dest, record, true, log)
+	return restoreDone
+}
+
+// writeRestored writes r to dest through dest + ".tmp", and only renames it
+// into place when the content matches record's checksum.
+func writeRestored(r io.Reader, dest string, record *BackupRecord) error {
+	h, err := newHasher(types.HashAlgorithm(record.HashAlgorithm))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	tmp := dest + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	renamed := false
+	defer func() {
+		_ = out.Close()
+		if !renamed {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	if _, err := io.Copy(out, io.TeeReader(r, h)); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); record.Hash != "" && sum != record.Hash {
+		return fmt.Errorf("%w: archived file does not match its manifest checksum", errChecksumMismatch)
+	}
+
+	if err := os.Rename(tmp, dest); err != nil {
+		return err
+	}
+	renamed = true
+	return nil
+}
+
+// restoreDestination applies the conflict policy to dest. It returns the
+// path to restore to, or false with the outcome when nothing is to be written
+// (an existing file is skipped, no free name is left, or this is a dry run).
+func restoreDestination(backupPath, dest string, opts types.RestoreOptions, log *logging.Logger) (string, restoreOutcome, bool) {
+	if DoesFileExist(dest) {
+		switch opts.Conflict {
+		case types.RestoreOverwrite:
+			// Proceed; the copy replaces dest only after it is complete.
+		case types.RestoreRename:
+			renamed := ""
+			for n := 2; n <= maxBackupVersions; n++ {
+				if candidate := versionedPath(dest, n); !DoesFileExist(candidate) {
+					renamed = candidate
+					break
+				}
+			}
+			if renamed == "" {
+				log.Errorf("No free name to restore %s next to %s", backupPath, dest)
+				return "", restoreFailed, false
+			}
+			dest = renamed
+		default:
+			log.Infof("Destination exists, skipping: %s", dest)
+			return "", restoreSkipped, false
+		}
+	}
+
+	if opts.DryRun {
+		log.Infof("Would restore: %s -> %s", backupPath, dest)
+		return "", restoreDone, false
+	}
+
+	return dest, restoreDone, true
+}
+
+// finishRestore logs a restored file and, with setModTime, gives it back its
+// recorded modification time. Callers that reapplied the backup's metadata
+// pass false: it already set both times, and setting them here again would
+// replace the access time.
+func finishRestore(backupPath, dest string, record *BackupRecord, setModTime bool, log *logging.Logger) {
+	// Restore the original modification time so the file does not look new
+	// and is not immediately treated as recent by retention rules.
+	if setModTime && record != nil && !record.SourceModTime.IsZero() {
+		if err := os.Chtimes(dest, record.SourceModTime, record.SourceModTime); err != nil {
+			log.Warnf("Could not restore modification time of %s: %v", dest, err)
+		}
+	}
+
+	log.Successf("Restored: %s -> %s", backupPath, dest)
+}
+
+// recordFormat returns how the backup described by record is stored. Backups
+// without a record are treated as plain copies.
+func recordFormat(record *BackupRecord, key *types.BackupKey) (storedFormat, error) {
+	if record == nil {
+		return storedFormat{}, nil
+	}
+	f := storedFormat{compress: types.Compression(record.Compression)}
+	if record.Encryption != "" {
+		if record.Encryption != encAlgorithm {
+			return storedFormat{}, fmt.Errorf("unsupported encryption %q", record.Encryption)
+		}
+		if key == nil {
+			return storedFormat{}, fmt.Errorf("backup is encrypted with key %s but no [backup] encrypt-key is configured", record.KeyID)
+		}
+		f.key = key
+	}
+	return f, nil
+}
+
+// parseRestoreDates parses "30Jan26" or "01Jan26..31Jan26" into an inclusive
+// date range.
+func parseRestoreDates(spec string) (from, to time.Time, err error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("no restore date given")
+	}
+
+	fromStr, toStr, isRange := strings.Cut(spec, "..")
+	if !isRange {
+		toStr = fromStr
+	}
+
+	from, err = time.ParseInLocation(backupDateLayout, strings.TrimSpace(fromStr), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid restore date %q (expected e.g. 30Jan26)", fromStr)
+	}
+	to, err = time.ParseInLocation(backupDateLayout, strings.TrimSpace(toStr), time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid restore date %q (expected e.g. 30Jan26)", toStr)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("restore range %s ends before it starts", spec)
+	}
+
+	return from, to, nil
+}
+
+// selectBackupDateFolders returns the dated folders under backupRoot that fall
+// within [from, to], oldest first. Folders whose names are not dates are ignored.
+func selectBackupDateFolders(backupRoot string, from, to time.Time) ([]string, error) {
+	entries, err := os.ReadDir(backupRoot)
+	if err != nil {
+		return nil, fmt.Errorf("read backup root: %w", err)
+	}
+
+	type dated struct {
+		path string
+		date time.Time
+	}
+	var folders []dated
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		date, ok := parseBackupDateFolder(e.Name())
+		if !ok {
+			continue
+		}
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		folders = append(folders, dated{path: filepath.Join(backupRoot, e.Name()), date: date})
+	}
+
+	sort.Slice(folders, func(i, j int) bool { return folders[i].date.Before(folders[j].date) })
+
+	paths := make([]string, 0, len(folders))
+	for _, f := range folders {
+		paths = append(paths, f.path)
+	}
+	return paths, nil
+}
+
+// loadRestoreRecords reads dateDir's manifest.jsonl, keyed by the backup path
+// relative to the dated folder (slash-separated). Keying by the relative path
+// keeps records usable after the backup root was moved or remapped.
+//
+// Records of archived files (layout=archive) share their archive's backup
+// path, so they are returned in archived instead, keyed by the archive's
+// relative path and then by member name.
+//
+// When a backup path (or archive member) appears more than once (e.g. a reused
+// copy), the last record wins. A missing manifest yields empty maps.
+func loadRestoreRecords(dateDir string, log *logging.Logger) (out map[string]BackupRecord, archived map[string]map[string]BackupRecord) {
+	out = make(map[string]BackupRecord)
+	archived = make(map[string]map[string]BackupRecord)
+
+	records, skipped, err := ReadBackupManifest(filepath.Join(dateDir, backupManifestName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Could not read manifest in %s, using backup layout only: %v", dateDir, err)
+		}
+		return out, archived
+	}
+	if skipped > 0 {
+		log.Warnf("Skipped %d unreadable manifest line(s) in %s", skipped, dateDir)
+	}
+
+	for _, rec := range records {
+		rel, ok := relInDateFolder(rec.BackupPath, dateDir)
+		switch {
+		case !ok:
+		case rec.Archive != "":
+			if archived[rel] == nil {
+				archived[rel] = make(map[string]BackupRecord)
+			}
+			archived[rel][rec.Member] = rec
+		default:
+			out[rel] = rec
+		}
+	}
+	return out, archived
+}
+
+// relInDateFolder returns the slash-separated part of backupPath inside
+// dateDir, e.g. ".../30Jan26/scans/a.pdf" -> "scans/a.pdf".
+//
+// The path is taken relative to dateDir's backup root, so a source folder
+// that happens to be named like the dated folder ("logs/30Jan26/app.log")
+// stays part of it. A record written before the backup root was moved or
+// remapped has another root; its dated folder is then the first path
+// component with that name.
+func relInDateFolder(backupPath, dateDir string) (string, bool) {
+	dateFolder := filepath.Base(dateDir)
+	if rel, err := filepath.Rel(filepath.Dir(dateDir), backupPath); err == nil {
+		first, rest, ok := strings.Cut(filepath.ToSlash(rel), "/")
+		if ok && first == dateFolder {
+			return rest, true
+		}
+	}
+
+	p := filepath.ToSlash(backupPath)
+	marker := "/" + dateFolder + "/"
+	i := strings.Index(p, marker)
+	if i < 0 {
+		return "", false
+	}
+	return p[i+len(marker):], true
+}
+
+// originalFromLayout rebuilds a file's original path from its backup layout
+// (<folder-name>/<relative-path>) using the configured [paths] entries.
+//
+// buildBackupPath keeps only the base name of the folder root, so the root is
+// found by matching that name against configured paths: folder entries by their
+// own base name, single-file entries by their parent's base name and file name.
+func originalFromLayout(rel string, pathconfig []types.PathConfig) (string, error) {
+	folderName, rest, ok := strings.Cut(filepath.ToSlash(rel), "/")
+	if !ok || rest == "" {
+		return "", fmt.Errorf("unexpected backup layout %q", rel)
+	}
+
+	roots := make(map[string]bool)
+	for _, pc := range pathconfig {
+		root := pc.Path
+		if !pc.IsDir {
+			root = filepath.Dir(pc.Path)
+			if foldCase(filepath.Base(pc.Path)) != foldCase(rest) {
+				continue
+			}
+		}
+		if foldCase(filepath.Base(root)) == foldCase(folderName) {
+			roots[filepath.Clean(root)] = true
+		}
+	}
+
+	switch len(roots) {
+	case 0:
+		return "", fmt.Errorf("no configured path named %q and no manifest record; use -restore-target", folderName)
+	case 1:
+		for root := range roots {
+			return filepath.Join(root, filepath.FromSlash(rest)), nil
+		}
+	}
+	return "", fmt.Errorf("several configured paths are named %q and there is no manifest record; use -restore-target", folderName)
+}
+
+// matchRestorePattern matches a -restore-match glob against a path using the
+// [paths] include/exclude glob rules (see pathFilter).
+func matchRestorePattern(pattern, p string) bool {
+	pattern = strings.Trim(foldCase(filepath.ToSlash(pattern)), "/")
+	return matchGlob(pattern, strings.Trim(normalizeRel(p), "/"))
+}
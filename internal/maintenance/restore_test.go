@@ -0,0 +1,291 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestParseRestoreDates_Table(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		wantFrom string
+		wantTo   string
+		wantErr  bool
+	}{
+		{name: "single day", spec: "30Jan26", wantFrom: "30Jan26", wantTo: "30Jan26"},
+		{name: "range", spec: "01Jan26..31Jan26", wantFrom: "01Jan26", wantTo: "31Jan26"},
+		{name: "range with spaces", spec: " 01Jan26 .. 05Jan26 ", wantFrom: "01Jan26", wantTo: "05Jan26"},
+		{name: "empty", spec: "", wantErr: true},
+		{name: "wrong format", spec: "2026-01-30", wantErr: true},
+		{name: "reversed range", spec: "31Jan26..01Jan26", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := parseRestoreDates(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (from=%v to=%v)", from, to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if from.Format(backupDateLayout) != tt.wantFrom || to.Format(backupDateLayout) != tt.wantTo {
+				t.Fatalf("want %s..%s, got %s..%s", tt.wantFrom, tt.wantTo, from.Format(backupDateLayout), to.Format(backupDateLayout))
+			}
+		})
+	}
+}
+
+func TestOriginalFromLayout_Table(t *testing.T) {
+	images := filepath.Join("C:", "Data", "Images")
+	pathconfig := []types.PathConfig{
+		{Path: images, IsDir: true},
+		{Path: filepath.Join("D:", "one", "Exports"), IsDir: true},
+		{Path: filepath.Join("D:", "two", "Exports"), IsDir: true},
+		{Path: filepath.Join("E:", "Logs", "app.log"), IsDir: false},
+	}
+
+	tests := []struct {
+		name    string
+		rel     string
+		want    string
+		wantErr bool
+	}{
+		{name: "folder entry", rel: "Images/2024/IMG001.jpg", want: filepath.Join(images, "2024", "IMG001.jpg")},
+		{name: "single-file entry", rel: "Logs/app.log", want: filepath.Join("E:", "Logs", "app.log")},
+		{name: "unknown folder", rel: "Scans/a.pdf", wantErr: true},
+		{name: "ambiguous folder", rel: "Exports/a.csv", wantErr: true},
+		{name: "no relative path", rel: "Images", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := originalFromLayout(filepath.FromSlash(tt.rel), pathconfig)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (got=%q)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRelInDateFolder_Table(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "backup")
+	dateDir := filepath.Join(root, "05Mar25")
+
+	tests := []struct {
+		name       string
+		backupPath string
+		want       string
+		wantOK     bool
+	}{
+		{name: "plain", backupPath: filepath.Join(dateDir, "scans", "a.pdf"), want: "scans/a.pdf", wantOK: true},
+		{name: "subfolder named like the date", backupPath: filepath.Join(dateDir, "logs", "05Mar25", "app.log"), want: "logs/05Mar25/app.log", wantOK: true},
+		{name: "moved backup root", backupPath: filepath.Join(t.TempDir(), "old", "05Mar25", "logs", "05Mar25", "app.log"), want: "logs/05Mar25/app.log", wantOK: true},
+		{name: "other dated folder", backupPath: filepath.Join(root, "06Mar25", "scans", "a.pdf"), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := relInDateFolder(tt.backupPath, dateDir)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("relInDateFolder(%q) = %q, %v; want %q, %v", tt.backupPath, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// backUpAndDelete runs the worker once so src's old files end up in backup
+// (with a manifest) and are removed from src.
+func backUpAndDelete(t *testing.T, root, src, backup string, files map[string]string) []types.PathConfig {
+	t.Helper()
+
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+	cfg.Backup = types.DefaultBackupOptions()
+	cfg.Backup.Move = false // copy, so records carry checksums to check on restore
+
+	for rel, contents := range files {
+		p := filepath.Join(src, filepath.FromSlash(rel))
+		mustMkdirAll(t, filepath.Dir(p))
+		mustWriteFile(t, p, contents)
+		mustSetAgeDays(t, p, 10)
+	}
+
+	pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+	for rel := range files {
+		assertNotExists(t, filepath.Join(src, filepath.FromSlash(rel)))
+	}
+	return pathconfig
+}
+
+func assertFileContents(t *testing.T, p, want string) {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("read %q: %v", p, err)
+	}
+	if string(b) != want {
+		t.Fatalf("%q: want %q, got %q", p, want, b)
+	}
+}
+
+func TestRestore_Integration_ToOriginalLocations(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	pathconfig := backUpAndDelete(t, root, src, backup, map[string]string{
+		"a.txt":         "alpha",
+		"sub/b.pdf":     "bravo",
+		"sub/deep/c.md": "charlie",
+	})
+	_, log := newTestCfgAndLogger(t, root)
+
+	today := time.Now().Format(backupDateLayout)
+	opts := types.RestoreOptions{Dates: today, Match: "**/sub/**"}
+
+	summary, err := Restore(pathconfig, backup, opts, log)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if summary.Restored != 2 || summary.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	assertNotExists(t, filepath.Join(src, "a.txt"))
+	assertFileContents(t, filepath.Join(src, "sub", "b.pdf"), "bravo")
+	assertFileContents(t, filepath.Join(src, "sub", "deep", "c.md"), "charlie")
+
+	// The original modification time comes back from the manifest.
+	info, err := os.Stat(filepath.Join(src, "sub", "b.pdf"))
+	if err != nil {
+		t.Fatalf("stat restored file: %v", err)
+	}
+	if time.Since(info.ModTime()) < 9*24*time.Hour {
+		t.Fatalf("expected restored mtime to be ~10 days old, got %s", info.ModTime())
+	}
+}
+
+func TestRestore_Integration_DryRunAndConflicts(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	pathconfig := backUpAndDelete(t, root, src, backup, map[string]string{"a.txt": "from backup"})
+	_, log := newTestCfgAndLogger(t, root)
+
+	today := time.Now().Format(backupDateLayout)
+	dest := filepath.Join(src, "a.txt")
+
+	// Dry run writes nothing.
+	summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today, DryRun: true}, log)
+	if err != nil || summary.Restored != 1 {
+		t.Fatalf("dry run: summary=%+v err=%v", summary, err)
+	}
+	assertNotExists(t, dest)
+
+	// A file that reappeared at the original location.
+	mustWriteFile(t, dest, "local edit")
+
+	tests := []struct {
+		conflict types.RestoreConflict
+		check    func(t *testing.T)
+	}{
+		{types.RestoreSkip, func(t *testing.T) {
+			assertFileContents(t, dest, "local edit")
+		}},
+		{types.RestoreRename, func(t *testing.T) {
+			assertFileContents(t, dest, "local edit")
+			assertFileContents(t, versionedPath(dest, 2), "from backup")
+		}},
+		{types.RestoreOverwrite, func(t *testing.T) {
+			assertFileContents(t, dest, "from backup")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.conflict), func(t *testing.T) {
+			if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today, Conflict: tt.conflict}, log); err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+			tt.check(t)
+		})
+	}
+}
+
+func TestRestore_Integration_WithoutManifestUsesLayoutOrTarget(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	pathconfig := backUpAndDelete(t, root, src, backup, map[string]string{"sub/a.txt": "alpha"})
+	_, log := newTestCfgAndLogger(t, root)
+
+	today := time.Now().Format(backupDateLayout)
+	if err := os.Remove(filepath.Join(backup, today, backupManifestName)); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+
+	// Into a separate target, keeping the backup layout.
+	target := filepath.Join(root, "restored")
+	if _, err := Restore(nil, backup, types.RestoreOptions{Dates: today, Target: target}, log); err != nil {
+		t.Fatalf("restore to target: %v", err)
+	}
+	assertFileContents(t, filepath.Join(target, filepath.Base(src), "sub", "a.txt"), "alpha")
+	assertNotExists(t, filepath.Join(src, "sub", "a.txt"))
+
+	// Back to the original location, found through the configured [paths] entry.
+	if _, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log); err != nil {
+		t.Fatalf("restore to original: %v", err)
+	}
+	assertFileContents(t, filepath.Join(src, "sub", "a.txt"), "alpha")
+}
+
+func TestRestore_Integration_RecordedTmpFile(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	pathconfig := backUpAndDelete(t, root, src, backup, map[string]string{"x.tmp": "scratch"})
+	_, log := newTestCfgAndLogger(t, root)
+
+	// An unrecorded *.tmp next to it is a leftover and stays out of the restore.
+	today := time.Now().Format(backupDateLayout)
+	mustWriteFile(t, filepath.Join(backup, today, filepath.Base(src), "y.tmp"), "partial")
+
+	summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if summary.Restored != 1 || summary.Skipped != 0 || summary.Failed != 0 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	assertFileContents(t, filepath.Join(src, "x.tmp"), "scratch")
+	assertNotExists(t, filepath.Join(src, "y.tmp"))
+}
+
+func TestRestore_Integration_RefusesCorruptBackup(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	pathconfig := backUpAndDelete(t, root, src, backup, map[string]string{"a.txt": "alpha"})
+	_, log := newTestCfgAndLogger(t, root)
+
+	today := time.Now().Format(backupDateLayout)
+	mustWriteFile(t, filepath.Join(backup, today, filepath.Base(src), "a.txt"), "bitrot")
+
+	summary, err := Restore(pathconfig, backup, types.RestoreOptions{Dates: today}, log)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if summary.Failed != 1 || summary.Restored != 0 {
+		t.Fatalf("expected one failed file, got %+v", summary)
+	}
+	assertNotExists(t, filepath.Join(src, "a.txt"))
+}
@@ -0,0 +1,204 @@
+package maintenance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// copyProgressMinSize is the smallest file whose copy logs its progress.
+	copyProgressMinSize = 256 << 20
+
+	// copyProgressInterval is how often a large copy logs its progress.
+	copyProgressInterval = 30 * time.Second
+
+	// resumeChunk is how much of an interrupted copy is compared with its
+	// source at a time. A mismatch keeps everything before its chunk.
+	resumeChunk = 1 << 20
+
+	// partialSuffix ends the name of a copy in progress (see partialPath).
+	partialSuffix = ".partial"
+)
+
+// partialPath returns the temp file a copy to dstPath is written to before it
+// is renamed into place: ".<name>.partial" next to it. An interrupted copy is
+// kept there for the next run, so its name must not be one that a backup of
+// some other source file takes: "<name>.tmp" is the backup of any source
+// named that way. The leading dot also keeps it out of folder listings.
+func partialPath(dstPath string) string {
+	return filepath.Join(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+partialSuffix)
+}
+
+// isPartialName reports whether name is that of a copy in progress.
+func isPartialName(name string) bool {
+	return strings.HasPrefix(name, ".") && strings.HasSuffix(name, partialSuffix)
+}
+
+// copyControl is how the caller steers one streamCopy. The zero value copies
+// to completion, with no progress reports, and starts every copy afresh.
+type copyControl struct {
+	// ctx is checked between chunks; once it is done the copy stops with its
+	// error, at most one chunk later.
+	ctx context.Context
+
+	// resume keeps the temp file of a plain copy that was interrupted, and
+	// continues from it on the next call (see resumePartial). A caller that
+	// sets it removes the temp file when it gives up on the copy.
+	resume bool
+
+	// resumed, if set, is called with the number of bytes kept from an
+	// earlier attempt.
+	resumed func(offset int64)
+
+	// progress, if set, is called with the number of source bytes copied so
+	// far, at most once per copyProgressInterval.
+	progress func(done int64)
+
+	// throttle, if set, paces the copy: every chunk counts its bytes and one
+	// file operation.
+	throttle *throttle
+}
+
+// err returns why the copy must stop, or nil.
+func (c copyControl) err() error {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+// copyMeter counts the bytes of one copy for copyControl.progress and
+// copyControl.throttle.
+type copyMeter struct {
+	ctl  copyControl
+	done int64
+	next time.Time
+}
+
+func newCopyMeter(ctl copyControl, offset int64) *copyMeter {
+	return &copyMeter{ctl: ctl, done: offset, next: time.Now().Add(copyProgressInterval)}
+}
+
+// check returns the control's error, so loops can stop between chunks.
+func (m *copyMeter) check() error {
+	return m.ctl.err()
+}
+
+// add counts a chunk of n more bytes, reports progress when it is due, and
+// waits for the throttle. It returns the control's error if the copy must
+// stop while it waits.
+func (m *copyMeter) add(n int64) error {
+	m.done += n
+	if m.ctl.progress != nil && !time.Now().Before(m.next) {
+		m.ctl.progress(m.done)
+		m.next = time.Now().Add(copyProgressInterval)
+	}
+	return m.ctl.throttle.wait(m.ctl.ctx, n, 1)
+}
+
+// chunk caps a chunk size for the throttle (see throttle.chunk).
+func (m *copyMeter) chunk(n int) int {
+	return m.ctl.throttle.chunk(n)
+}
+
+// meteredReader reads through a copyMeter: every Read first checks whether the
+// copy must stop, and is paced by the throttle afterwards.
+type meteredReader struct {
+	r io.Reader
+	m *copyMeter
+}
+
+func (r meteredReader) Read(p []byte) (int, error) {
+	if err := r.m.check(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if mErr := r.m.add(int64(n)); mErr != nil && err == nil {
+			err = mErr
+		}
+	}
+	return n, err
+}
+
+// resumePartial opens tmp, the temp file of an earlier copy of in that was
+// interrupted, to continue writing it. Its content is trusted only as far as
+// it matches in: both are read from the start in resumeChunk pieces, and tmp
+// is cut off before the first piece that differs. A missing tmp is created.
+//
+// It returns tmp opened for writing and the number of bytes kept. Both files
+// are positioned after those bytes, and they were written to h, so the copy
+// continues exactly as if it had not stopped.
+func resumePartial(in *os.File, tmp string, h io.Writer, ctl copyControl) (*os.File, int64, error) {
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, 0, err
+	}
+	fail := func(err error) (*os.File, int64, error) {
+		_ = out.Close()
+		return nil, 0, err
+	}
+
+	kept, err := matchingPrefix(in, out, h, ctl)
+	if err != nil {
+		return fail(err)
+	}
+	if err := out.Truncate(kept); err != nil {
+		return fail(err)
+	}
+	if _, err := out.Seek(kept, io.SeekStart); err != nil {
+		return fail(err)
+	}
+	if _, err := in.Seek(kept, io.SeekStart); err != nil {
+		return fail(err)
+	}
+	return out, kept, nil
+}
+
+// matchingPrefix reads partial and src from their current positions and
+// returns how many bytes of partial, in whole resumeChunk pieces (or its
+// shorter last piece), are identical to src. The matching bytes are written
+// to h.
+func matchingPrefix(src, partial io.Reader, h io.Writer, ctl copyControl) (int64, error) {
+	want := make([]byte, resumeChunk)
+	got := make([]byte, resumeChunk)
+
+	var kept int64
+	for {
+		if err := ctl.err(); err != nil {
+			return 0, err
+		}
+
+		n, err := io.ReadFull(partial, got)
+		if n == 0 {
+			if err == io.EOF {
+				return kept, nil
+			}
+			return 0, err
+		}
+		last := err == io.ErrUnexpectedEOF
+		if err != nil && !last {
+			return 0, err
+		}
+
+		m, err := io.ReadFull(src, want[:n])
+		if err != nil && !errors.Is(err, io.EOF) && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		if m != n || !bytes.Equal(want[:n], got[:n]) {
+			return kept, nil
+		}
+
+		_, _ = h.Write(got[:n])
+		kept += int64(n)
+		if last {
+			return kept, nil
+		}
+	}
+}
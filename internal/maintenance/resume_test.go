@@ -0,0 +1,223 @@
+package maintenance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestCopyfileStream_ResumesPartialCopy(t *testing.T) {
+	data := make([]byte, 3*resumeChunk+7)
+	for i := range data {
+		data[i] = byte(i * 31)
+	}
+	corrupted := bytes.Clone(data[:2*resumeChunk])
+	corrupted[resumeChunk+5] ^= 0xff
+
+	tests := []struct {
+		name       string
+		partial    []byte // nil: no temp file
+		wantOffset int64
+	}{
+		{name: "no partial copy", partial: nil, wantOffset: 0},
+		{name: "matching prefix", partial: data[:resumeChunk+resumeChunk/2], wantOffset: resumeChunk + resumeChunk/2},
+		{name: "damaged second chunk", partial: corrupted, wantOffset: resumeChunk},
+		{name: "another file", partial: []byte("something else"), wantOffset: 0},
+		{name: "longer than source", partial: append(bytes.Clone(data), "tail"...), wantOffset: 3 * resumeChunk},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src.bin")
+			dst := filepath.Join(dir, "out", "dst.bin")
+			if err := os.WriteFile(src, data, 0o644); err != nil {
+				t.Fatalf("write source: %v", err)
+			}
+			mustMkdirAll(t, filepath.Dir(dst))
+			if tt.partial != nil {
+				if err := os.WriteFile(partialPath(dst), tt.partial, 0o644); err != nil {
+					t.Fatalf("write partial copy: %v", err)
+				}
+			}
+
+			var offset int64
+			ctl := copyControl{resume: true, resumed: func(n int64) { offset = n }}
+			sum, err := copyfileStream(src, dst, types.BackupOptions{Verify: true}, ctl)
+			if err != nil {
+				t.Fatalf("copy: %v", err)
+			}
+			if offset != tt.wantOffset {
+				t.Fatalf("resumed at %d, want %d", offset, tt.wantOffset)
+			}
+
+			want, err := hashFile(src, "")
+			if err != nil || sum != want {
+				t.Fatalf("checksum %s, want %s (err=%v)", sum, want, err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("copy differs from source (err=%v)", err)
+			}
+			assertNotExists(t, partialPath(dst))
+		})
+	}
+}
+
+func TestCopyfileStream_StopsWhenCancelled(t *testing.T) {
+	tests := []struct {
+		name        string
+		resume      bool
+		wantPartial bool
+	}{
+		{name: "resumable copy keeps its temp file", resume: true, wantPartial: true},
+		{name: "other copies remove it", resume: false, wantPartial: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src.txt")
+			dst := filepath.Join(dir, "dst.txt")
+			mustWriteFile(t, src, "large scan")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := copyfileStream(src, dst, types.BackupOptions{}, copyControl{ctx: ctx, resume: tt.resume})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			assertNotExists(t, dst)
+			if tt.wantPartial {
+				assertExists(t, partialPath(dst))
+			} else {
+				assertNotExists(t, partialPath(dst))
+			}
+		})
+	}
+}
+
+func TestCopyFileWithRetry_KeepsPartialCopyWhenStopped(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	_, log := newTestCfgAndLogger(t, root)
+
+	srcFile := filepath.Join(src, "a.txt")
+	dst := filepath.Join(backup, "a.txt")
+	mustWriteFile(t, srcFile, "payload")
+	mustWriteFile(t, partialPath(dst), "pay")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := copyFileWithRetry(ctx, srcFile, dst, 2, types.BackupOptions{}, nil, log); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	assertNotExists(t, dst)
+	assertFileContents(t, partialPath(dst), "pay")
+}
+
+func TestCopyFileWithRetry_RemovesPartialCopyWhenGivingUp(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	_, log := newTestCfgAndLogger(t, root)
+
+	dst := filepath.Join(backup, "a.txt")
+	mustWriteFile(t, partialPath(dst), "pay")
+
+	if _, err := copyFileWithRetry(context.Background(), filepath.Join(src, "missing.txt"), dst, 0, types.BackupOptions{}, nil, log); err == nil {
+		t.Fatalf("expected an error for a missing source")
+	}
+	assertNotExists(t, partialPath(dst))
+}
+
+func TestCopyFileWithRetry_ResumesAfterStop(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	_, log := newTestCfgAndLogger(t, root)
+
+	data := make([]byte, 4*resumeChunk)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	srcFile := filepath.Join(src, "scan.bin")
+	dst := filepath.Join(backup, "scan.bin")
+	if err := os.WriteFile(srcFile, data, 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	// At 1 MiB/s the first MiB is the throttle's burst and the copy then
+	// waits, so the deadline stops it part way through.
+	thr := newThrottle(types.ThrottleOptions{MaxBandwidth: resumeChunk})
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := copyFileWithRetry(ctx, srcFile, dst, 2, types.BackupOptions{}, thr, log); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	assertNotExists(t, dst)
+	partial, err := os.ReadFile(partialPath(dst))
+	if err != nil {
+		t.Fatalf("partial copy was not kept: %v", err)
+	}
+	if len(partial) == 0 || len(partial) >= len(data) || !bytes.Equal(partial, data[:len(partial)]) {
+		t.Fatalf("partial copy of %d bytes is not a prefix of the source", len(partial))
+	}
+
+	sum, err := copyFileWithRetry(context.Background(), srcFile, dst, 2, types.BackupOptions{Verify: true}, nil, log)
+	if err != nil {
+		t.Fatalf("resumed copy: %v", err)
+	}
+	want, err := hashFile(srcFile, "")
+	if err != nil || sum != want {
+		t.Fatalf("checksum %s, want %s (err=%v)", sum, want, err)
+	}
+	got, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("resumed copy differs from source (err=%v)", err)
+	}
+	assertNotExists(t, partialPath(dst))
+}
+
+func TestCopyMeter_ReportsProgress(t *testing.T) {
+	var reported []int64
+	m := newCopyMeter(copyControl{progress: func(done int64) { reported = append(reported, done) }}, 100)
+
+	m.add(10)
+	if len(reported) != 0 {
+		t.Fatalf("progress reported before the interval: %v", reported)
+	}
+
+	m.next = time.Now().Add(-time.Second)
+	m.add(10)
+	if len(reported) != 1 || reported[0] != 120 {
+		t.Fatalf("reported %v, want [120]", reported)
+	}
+}
+
+func TestCopyFileWithRetry_LeavesBackupNamedTmpAlone(t *testing.T) {
+	root, src, backup := newSandbox(t)
+	_, log := newTestCfgAndLogger(t, root)
+
+	// The backup of a source file that is itself named "a.txt.tmp".
+	dst := filepath.Join(backup, "a.txt")
+	mustWriteFile(t, dst+".tmp", "another file")
+
+	srcFile := filepath.Join(src, "a.txt")
+	mustWriteFile(t, srcFile, "payload")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := copyFileWithRetry(ctx, srcFile, dst, 0, types.BackupOptions{}, nil, log); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := copyFileWithRetry(context.Background(), srcFile, dst, 0, types.BackupOptions{}, nil, log); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+
+	assertFileContents(t, dst, "payload")
+	assertFileContents(t, dst+".tmp", "another file")
+	assertNotExists(t, partialPath(dst))
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"file-maintenance/internal/logging"
 )
 
 // RemoveOldLogs deletes log files older than `days` inside logPath.
@@ -93,3 +96,81 @@ func RemoveOldLogs(logPath string, days int) error {
 
 	return nil
 }
+
+// RemoveOldBackups deletes dated backup folders under backupRoot whose folder
+// date is more than `days` days before today, and returns how many were removed.
+//
+// Behavior:
+//   - Only top-level directories named exactly in the 02Jan06 layout are
+//     considered (e.g. 30Jan26). Anything else under backupRoot, including
+//     files, unparseable folder names, and folders dated in the future, is
+//     never touched.
+//   - Age comes from the folder NAME, not its modification time: copying old
+//     backups, restores, or antivirus scans change mtimes, names do not.
+//   - A folder dated D is removed when D is strictly before today - days (local
+//     time), so retention=1 keeps today's and yesterday's folders.
+//   - Best-effort per folder: a folder that cannot be removed is logged and the
+//     rest are still processed.
+//   - When folders were removed and backupRoot has an objects store
+//     (layout=objects), objects no remaining manifest refers to are removed
+//     too (see pruneUnreferencedObjects).
+//
+// Error behavior:
+//   - days <= 0 is a no-op (retention disabled).
+//   - Returns an error only when backupRoot itself cannot be read.
+func RemoveOldBackups(backupRoot string, days int, log *logging.Logger) (int, error) {
+	if days <= 0 {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(backupRoot)
+	if err != nil {
+		return 0, fmt.Errorf("read backup root: %w", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	cutoff := today.AddDate(0, 0, -days)
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		date, ok := parseBackupDateFolder(entry.Name())
+		if !ok || !date.Before(cutoff) {
+			continue
+		}
+
+		full := filepath.Join(backupRoot, entry.Name())
+		if err := os.RemoveAll(full); err != nil {
+			log.Errorf("Could not remove expired backup folder %s: %v", full, err)
+			continue
+		}
+		log.Successf("Removed expired backup folder: %s", full)
+		removed++
+	}
+
+	if removed > 0 {
+		objects, err := pruneUnreferencedObjects(backupRoot, log)
+		if err != nil {
+			log.Errorf("Could not prune backup objects in %s: %v", backupRoot, err)
+		} else if objects > 0 {
+			log.Countf("Amount of unreferenced backup objects removed from %s: %d", backupRoot, objects)
+		}
+	}
+
+	return removed, nil
+}
+
+// parseBackupDateFolder parses a dated backup folder name (02Jan06) in local
+// time. Names that do not round-trip exactly are rejected, so only folders this
+// tool created are ever treated as dated backups.
+func parseBackupDateFolder(name string) (time.Time, bool) {
+	date, err := time.ParseInLocation(backupDateLayout, name, time.Local)
+	if err != nil || date.Format(backupDateLayout) != name {
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,76 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseBackupDateFolder_Table(t *testing.T) {
+	tests := []struct {
+		name   string
+		folder string
+		wantOK bool
+	}{
+		{name: "dated folder", folder: "30Jan26", wantOK: true},
+		{name: "lowercase month", folder: "30jan26"},
+		{name: "single-digit day", folder: "1Jan26"},
+		{name: "iso date", folder: "2026-01-30"},
+		{name: "suffix", folder: "30Jan26-old"},
+		{name: "not a date", folder: "manual"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := parseBackupDateFolder(tt.folder)
+			if ok != tt.wantOK {
+				t.Fatalf("parseBackupDateFolder(%q) ok=%v, want %v", tt.folder, ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRemoveOldBackups_Integration(t *testing.T) {
+	root, _, backup := newSandbox(t)
+	_, log := newTestCfgAndLogger(t, root)
+
+	folderFor := func(daysAgo int) string {
+		return filepath.Join(backup, time.Now().AddDate(0, 0, -daysAgo).Format(backupDateLayout))
+	}
+
+	expired := []string{folderFor(31), folderFor(400)}
+	kept := []string{
+		folderFor(0),
+		folderFor(30),
+		folderFor(-1), // dated in the future
+		filepath.Join(backup, "manual"),
+		filepath.Join(backup, "30jan26"),
+	}
+	for _, dir := range append(append([]string{}, expired...), kept...) {
+		mustMkdirAll(t, dir)
+		mustWriteFile(t, filepath.Join(dir, "a.txt"), "x")
+		// Folder mtimes must not matter: make every folder look ancient.
+		mustSetAgeDays(t, dir, 1000)
+	}
+	mustWriteFile(t, filepath.Join(backup, "01Jan20"), "a file, not a folder")
+
+	removed, err := RemoveOldBackups(backup, 30, log)
+	if err != nil {
+		t.Fatalf("RemoveOldBackups: %v", err)
+	}
+	if removed != len(expired) {
+		t.Fatalf("want %d removed, got %d", len(expired), removed)
+	}
+	for _, dir := range expired {
+		assertNotExists(t, dir)
+	}
+	for _, dir := range kept {
+		assertExists(t, filepath.Join(dir, "a.txt"))
+	}
+	assertExists(t, filepath.Join(backup, "01Jan20"))
+
+	// Retention 0 keeps everything.
+	if removed, err := RemoveOldBackups(backup, 0, log); err != nil || removed != 0 {
+		t.Fatalf("retention 0: removed=%d err=%v", removed, err)
+	}
+}
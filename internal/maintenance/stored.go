@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"encoding/hex"
+	"io"
+	"os"
+
+	"file-maintenance/internal/types"
+)
+
+// storedFormat is how a backup file's bytes relate to the original content:
+// optionally compressed, then optionally encrypted.
+type storedFormat struct {
+	compress types.Compression
+	key      *types.BackupKey // encrypted with this key when non-nil
+}
+
+// formatOf returns the format backups written with opts are stored in.
+func formatOf(opts types.BackupOptions) storedFormat {
+	return storedFormat{compress: opts.Compress, key: opts.Key}
+}
+
+// plain reports whether the stored bytes are the original content.
+func (f storedFormat) plain() bool {
+	return !isCompressed(f.compress) && f.key == nil
+}
+
+// storedName returns the backup file name for dstPath in format f,
+// e.g. "export.csv" -> "export.csv.gz.enc".
+func storedName(dstPath string, f storedFormat) string {
+	if isCompressed(f.compress) {
+		dstPath += gzipSuffix
+	}
+	if f.key != nil {
+		dstPath += encSuffix
+	}
+	return dstPath
+}
+
+// chainWriter writes through the outermost of several layered writers and
+// closes them outermost first, so each layer flushes into the next.
+type chainWriter struct {
+	io.Writer
+	layers []io.Closer
+}
+
+func (c chainWriter) Close() error {
+	for _, l := range c.layers {
+		if err := l.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newStoredWriter wraps w so that original content written to it is stored
+// in format f. level and name are passed to newCompressWriter.
+//
+// Close must be called before the underlying file is closed.
+func newStoredWriter(w io.Writer, f storedFormat, level int, name string) (io.WriteCloser, error) {
+	var layers []io.Closer
+	if f.key != nil {
+		enc, err := newEncryptWriter(w, f.key)
+		if err != nil {
+			return nil, err
+		}
+		w = enc
+		layers = append(layers, enc)
+	}
+
+	zw, err := newCompressWriter(w, f.compress, level, name)
+	if err != nil {
+		return nil, err
+	}
+	// Compress first, then encrypt: ciphertext does not compress.
+	layers = append([]io.Closer{zw}, layers...)
+
+	return chainWriter{Writer: zw, layers: layers}, nil
+}
+
+// openStored wraps r, the bytes of a backup stored in format f, so that
+// reading from it yields the original content.
+func openStored(r io.Reader, f storedFormat) (io.ReadCloser, error) {
+	if f.key != nil {
+		dec, err := newDecryptReader(r, f.key)
+		if err != nil {
+			return nil, err
+		}
+		r = dec
+	}
+	return newDecompressReader(r, f.compress)
+}
+
+// hashStored returns the alg checksum of the original content stored in path
+// in format f. Checksums in the manifest always describe the original
+// content, so plain, compressed, and encrypted backups of the same file
+// compare equal.
+//
+// The file is read through ctl, so the read counts against its throttle and
+// stops with its context; the zero copyControl reads at full speed.
+func hashStored(path string, alg types.HashAlgorithm, f storedFormat, ctl copyControl) (string, error) {
+	h, err := newHasher(alg)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	r, err := openStored(meteredReader{r: file, m: newCopyMeter(ctl, 0)}, f)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	buf := make([]byte, 256*1024)
+	if _, err := io.CopyBuffer(h, r, buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package maintenance
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// syncDirectory fsyncs the directory dir. Filesystems that cannot sync a
+// directory (some FUSE and network filesystems) report EINVAL or an
+// unsupported operation; there is nothing more to do on those, so it is not
+// an error.
+func syncDirectory(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package maintenance
+
+// syncDirectory does nothing on Windows: directories cannot be flushed
+// there, and NTFS journals directory changes itself. Flushing the file
+// (syncFile) is what makes its content durable.
+func syncDirectory(dir string) error {
+	return nil
+}
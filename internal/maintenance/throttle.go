@@ -0,0 +1,118 @@
+package maintenance
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+// throttle limits backup I/O with two token buckets, one for bytes
+// ([advanced] max-bandwidth) and one for file operations
+// (max-ops-per-second). A run has one throttle, shared by every copy, move
+// and delete, so the limits hold for the run as a whole however the work is
+// split into files and chunks.
+//
+// Each bucket holds at most one second of its rate, so after a pause at most
+// one second's worth goes out at full speed. Tokens are taken after the I/O
+// they stand for and a bucket may go into debt: a chunk larger than the
+// bucket is followed by a wait until the debt is paid, which keeps the
+// average rate.
+//
+// Inside a full-speed window nothing is limited. A nil *throttle does not
+// limit anything either.
+type throttle struct {
+	mu        sync.Mutex
+	bytes     tokenBucket
+	ops       tokenBucket
+	fullSpeed []types.TimeWindow
+
+	// now is time.Now, replaced in tests.
+	now func() time.Time
+}
+
+// tokenBucket is one limit of a throttle.
+type tokenBucket struct {
+	rate   float64 // tokens per second; 0 is unlimited
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket up to now, removes n tokens, and returns how long
+// the caller has to wait for the bucket to be out of debt.
+func (b *tokenBucket) take(n float64, now time.Time) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// newThrottle returns the throttle for opts, or nil when opts sets no limit.
+func newThrottle(opts types.ThrottleOptions) *throttle {
+	if !opts.Limited() {
+		return nil
+	}
+	now := time.Now()
+	return &throttle{
+		bytes:     tokenBucket{rate: float64(opts.MaxBandwidth), tokens: float64(opts.MaxBandwidth), last: now},
+		ops:       tokenBucket{rate: float64(opts.MaxOpsPerSecond), tokens: float64(opts.MaxOpsPerSecond), last: now},
+		fullSpeed: opts.FullSpeed,
+		now:       time.Now,
+	}
+}
+
+// delay accounts for I/O of n bytes in ops file operations and returns how
+// long to pause before the next.
+func (t *throttle) delay(n int64, ops int) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, w := range t.fullSpeed {
+		if w.Contains(now) {
+			return 0
+		}
+	}
+	return max(t.bytes.take(float64(n), now), t.ops.take(float64(ops), now))
+}
+
+// wait accounts for I/O like delay and pauses for as long as it says. It
+// returns ctx's error, early, once ctx is done; a nil ctx never is.
+func (t *throttle) wait(ctx context.Context, n int64, ops int) error {
+	d := t.delay(n, ops)
+	if d <= 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chunk caps a chunk of n bytes at one second of bandwidth, so a single
+// kernel copy call cannot run far ahead of the limit.
+func (t *throttle) chunk(n int) int {
+	if t == nil || t.bytes.rate == 0 {
+		return n
+	}
+	return max(1, min(n, int(t.bytes.rate)))
+}
@@ -0,0 +1,222 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"file-maintenance/internal/types"
+)
+
+func TestThrottle_Delay(t *testing.T) {
+	base := time.Date(2026, 3, 2, 14, 0, 0, 0, time.Local)
+
+	type step struct {
+		after     time.Duration // since the previous step
+		bytes     int64
+		ops       int
+		wantDelay time.Duration
+	}
+	tests := []struct {
+		name  string
+		opts  types.ThrottleOptions
+		steps []step
+	}{
+		{
+			name: "bandwidth burst then debt",
+			opts: types.ThrottleOptions{MaxBandwidth: 100},
+			steps: []step{
+				{bytes: 100, wantDelay: 0},
+				{bytes: 50, wantDelay: 500 * time.Millisecond},
+				{after: 500 * time.Millisecond, bytes: 0, wantDelay: 0},
+				{after: 2 * time.Second, bytes: 300, wantDelay: 2 * time.Second},
+			},
+		},
+		{
+			name: "operations",
+			opts: types.ThrottleOptions{MaxOpsPerSecond: 2},
+			steps: []step{
+				{ops: 1, wantDelay: 0},
+				{ops: 1, wantDelay: 0},
+				{ops: 1, wantDelay: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "slower limit wins",
+			opts: types.ThrottleOptions{MaxBandwidth: 1000, MaxOpsPerSecond: 1},
+			steps: []step{
+				{bytes: 10, ops: 1, wantDelay: 0},
+				{bytes: 10, ops: 1, wantDelay: time.Second},
+			},
+		},
+		{
+			name: "full speed window",
+			opts: types.ThrottleOptions{MaxBandwidth: 100, FullSpeed: []types.TimeWindow{{Start: 13 * time.Hour, End: 15 * time.Hour}}},
+			steps: []step{
+				{bytes: 1000, wantDelay: 0},
+				{bytes: 1000, wantDelay: 0},
+			},
+		},
+		{
+			name: "outside full speed window",
+			opts: types.ThrottleOptions{MaxBandwidth: 100, FullSpeed: []types.TimeWindow{{Start: 22 * time.Hour, End: 6 * time.Hour}}},
+			steps: []step{
+				{bytes: 200, wantDelay: time.Second},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := base
+			thr := newThrottle(tt.opts)
+			thr.now = func() time.Time { return now }
+			thr.bytes.last, thr.ops.last = now, now
+
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+				if got := thr.delay(s.bytes, s.ops); got != s.wantDelay {
+					t.Fatalf("step %d: delay %s, want %s", i, got, s.wantDelay)
+				}
+			}
+		})
+	}
+}
+
+func TestThrottle_NilLimitsNothing(t *testing.T) {
+	thr := newThrottle(types.ThrottleOptions{FullSpeed: []types.TimeWindow{{Start: 0, End: time.Hour}}})
+	if thr != nil {
+		t.Fatalf("expected no throttle without limits")
+	}
+	if d := thr.delay(1<<40, 1000); d != 0 {
+		t.Fatalf("nil throttle delayed %s", d)
+	}
+	if n := thr.chunk(64 << 20); n != 64<<20 {
+		t.Fatalf("nil throttle changed chunk to %d", n)
+	}
+}
+
+func TestThrottle_WaitStopsWithContext(t *testing.T) {
+	thr := newThrottle(types.ThrottleOptions{MaxOpsPerSecond: 1})
+	_ = thr.wait(context.Background(), 0, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := thr.wait(ctx, 0, 10); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTimeWindow_Contains(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	night := types.TimeWindow{Start: 22 * time.Hour, End: 6 * time.Hour}
+	lunch := types.TimeWindow{Start: 12 * time.Hour, End: 13 * time.Hour}
+
+	tests := []struct {
+		w    types.TimeWindow
+		at   time.Duration
+		want bool
+	}{
+		{night, 23 * time.Hour, true},
+		{night, 3 * time.Hour, true},
+		{night, 6 * time.Hour, false},
+		{night, 22 * time.Hour, true},
+		{night, 12 * time.Hour, false},
+		{lunch, 12*time.Hour + 30*time.Minute, true},
+		{lunch, 13 * time.Hour, false},
+		{lunch, 11 * time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.w.Contains(day.Add(tt.at)); got != tt.want {
+			t.Fatalf("%v contains %s: got %v, want %v", tt.w, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestCopyfileStream_Throttled(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	dst := filepath.Join(dir, "dst.bin")
+	if err := os.WriteFile(src, make([]byte, 3<<19), 0o644); err != nil { // 1.5 MiB
+		t.Fatalf("write source: %v", err)
+	}
+
+	// 1 MiB/s: the first MiB is the bucket's burst, the rest has to wait.
+	thr := newThrottle(types.ThrottleOptions{MaxBandwidth: 1 << 20})
+	start := time.Now()
+	if _, err := copyfileStream(src, dst, types.BackupOptions{}, copyControl{throttle: thr}); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("throttled copy took %s, want about 500ms", elapsed)
+	}
+}
+
+func TestThrottle_CountsChecksumReads(t *testing.T) {
+	const size = 3 << 19 // 1.5 MiB
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.bin")
+	if err := os.WriteFile(src, make([]byte, size), 0o644); err != nil {
+		t.Fatalf("write source: %v", err)
+	}
+
+	// counting returns a throttle whose clock stands still, so its byte bucket
+	// is never refilled and never makes anyone wait, and a func reporting
+	// how many bytes were taken from it.
+	counting := func() (*throttle, func() int64) {
+		const rate = 1 << 40
+		thr := newThrottle(types.ThrottleOptions{MaxBandwidth: rate})
+		now := time.Now()
+		thr.now = func() time.Time { return now }
+		thr.bytes.last = now
+		return thr, func() int64 { return int64(rate - thr.bytes.tokens) }
+	}
+
+	// Copy without and with verification: the difference is the read-back,
+	// whether the copy itself ran in the kernel (and the source was hashed
+	// afterwards) or in user space.
+	copied := func(verify bool) int64 {
+		thr, taken := counting()
+		dst := filepath.Join(dir, "dst.bin")
+		defer os.Remove(dst)
+		if _, err := copyfileStream(src, dst, types.BackupOptions{Verify: verify}, copyControl{throttle: thr}); err != nil {
+			t.Fatalf("copy: %v", err)
+		}
+		return taken()
+	}
+	plain, verified := copied(false), copied(true)
+	if plain < size {
+		t.Fatalf("copy took %d bytes from the throttle, want at least %d", plain, size)
+	}
+	if verified-plain != size {
+		t.Fatalf("verification took %d bytes from the throttle, want %d", verified-plain, size)
+	}
+
+	// An existing backup of the same size is hashed, as is the source, to
+	// decide whether it can be reused.
+	existing := filepath.Join(dir, "backup", "src.bin")
+	mustMkdirAll(t, filepath.Dir(existing))
+	if err := os.WriteFile(existing, make([]byte, size), 0o644); err != nil {
+		t.Fatalf("write existing backup: %v", err)
+	}
+	thr, taken := counting()
+	target, err := resolveBackupTarget(src, existing, types.BackupOptions{}, copyControl{throttle: thr})
+	if err != nil || !target.reused {
+		t.Fatalf("expected the existing backup to be reused, got %+v (err=%v)", target, err)
+	}
+	if got := taken(); got != 2*size {
+		t.Fatalf("collision check took %d bytes from the throttle, want %d", got, 2*size)
+	}
+
+	thr, taken = counting()
+	if _, err := resolveObjectTarget(src, filepath.Join(dir, "objects-root"), types.BackupOptions{}, copyControl{throttle: thr}); err != nil {
+		t.Fatalf("resolve object: %v", err)
+	}
+	if got := taken(); got != size {
+		t.Fatalf("object lookup took %d bytes from the throttle, want %d", got, size)
+	}
+}
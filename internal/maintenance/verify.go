@@ -27,9 +27,17 @@ import (
 //
 //   - days == 0 means "older than now" which will match almost all existing files,
 //     except files with future timestamps.
+//
+// Per-path age bases other than modification time are handled by ageEvaluator,
+// which applies the same cutoff through isTimeOlder.
 func IsFileOlder(info os.FileInfo, days int) bool {
+	return isTimeOlder(info.ModTime(), days)
+}
+
+// isTimeOlder reports whether t is strictly before now - days.
+func isTimeOlder(t time.Time, days int) bool {
 	cutoff := time.Now().AddDate(0, 0, -days)
-	return info.ModTime().Before(cutoff)
+	return t.Before(cutoff)
 }
 
 // DoesFileExist checks whether a file or directory exists at the given path.
@@ -3,8 +3,11 @@ package maintenance
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,14 +38,136 @@ type FileJob struct {
 	//  2) per-folder deletion counting needs a stable key (folderRoot) for accurate reporting
 	folderRoot string
 
+	// configPath is the [paths] entry that selected this file.
+	//
+	// For folder entries this equals folderRoot. For single-file entries it is
+	// the file itself, while folderRoot is its parent directory. Per-path
+	// reporting is keyed by configPath so both cases are counted correctly.
+	configPath string
+
 	// backup indicates whether this file should be backed up before deletion.
 	// This is set based on the path's configuration in config.ini.
 	backup    bool
 	sizeBytes uint64
+	modTime   time.Time
+
+	// retries and cooldown are the effective per-path values for this job
+	// (see resolvePathSettings).
+	retries  int
+	cooldown time.Duration
+
+	// planned marks jobs fed from a reviewed plan (see ApplyPlan). The processor
+	// re-stats these files right before acting and skips any whose size or
+	// modification time no longer match sizeBytes/modTime.
+	planned bool
+
+	// dest is the path's own backup root (PathConfig.Dest), or "" for the
+	// [backup] destinations. Batches are space-checked per dest.
+	dest string
+
+	// compress is the effective compression for this file's backup
+	// (see resolvePathSettings).
+	compress types.Compression
+}
+
+// pathSettings holds the effective settings for one configured path after
+// per-path overrides from [paths] are applied on top of the run-wide config.
+type pathSettings struct {
+	days     int
+	retries  int
+	cooldown time.Duration
+	maxFiles int // 0 = unlimited for this path
+
+	// pruneEmptyDirs/pruneMinAge control the empty-directory pruning phase
+	// (see dirTracker). pruneMinAge is always set when pruneEmptyDirs is true.
+	pruneEmptyDirs bool
+	pruneMinAge    time.Duration
+
+	// compress is [backup] compress unless the path sets its own.
+	compress types.Compression
+}
+
+// resolvePathSettings applies PathConfig overrides to the run-wide values.
+//
+// Per-path values win over [settings]/[advanced] and over CLI flags, because
+// they are the more specific setting for that folder.
+func resolvePathSettings(pc types.PathConfig, cfg types.AppConfig) pathSettings {
+	s := pathSettings{
+		days:     cfg.Days,
+		retries:  cfg.Retries,
+		cooldown: cfg.Cooldown,
+		compress: cfg.Backup.Compress,
+	}
+	if pc.Compress != "" {
+		s.compress = pc.Compress
+	}
+	if pc.Days != nil {
+		s.days = *pc.Days
+	}
+	if pc.Retries != nil {
+		s.retries = *pc.Retries
+	}
+	if pc.Cooldown != nil {
+		s.cooldown = *pc.Cooldown
+	}
+	if pc.MaxFiles != nil {
+		s.maxFiles = *pc.MaxFiles
+	}
+	if pc.PruneEmptyDirs {
+		s.pruneEmptyDirs = true
+		s.pruneMinAge = defaultPruneMinAge
+		if pc.PruneMinAge != nil {
+			s.pruneMinAge = *pc.PruneMinAge
+		}
+	}
+	if s.retries < 0 {
+		s.retries = 0
+	}
+	return s
 }
 
 type diskSpaceChecker interface {
 	AvailableBytes(path string) (uint64, error)
+	TotalBytes(path string) (uint64, error)
+}
+
+// backupReserve returns the bytes that must stay free on the backup volume and
+// a description of the setting that demands them. When both min-free and
+// min-free-percent are set, the larger reserve wins.
+func backupReserve(opts types.BackupOptions, totalBytes uint64) (uint64, string) {
+	var reserve uint64
+	desc := ""
+
+	if opts.MinFreePercent > 0 {
+		reserve = uint64(float64(totalBytes) * opts.MinFreePercent / 100)
+		desc = fmt.Sprintf("min-free-percent=%g%% of %d bytes (%d bytes)", opts.MinFreePercent, totalBytes, reserve)
+	}
+	if opts.MinFree > reserve {
+		reserve = opts.MinFree
+		desc = fmt.Sprintf("min-free=%d bytes", opts.MinFree)
+	}
+
+	return reserve, desc
+}
+
+// batchTarget is where one destination group of a batch is copied: roots are
+// tried in order, and required of the copies must succeed before a source file
+// may be deleted.
+type batchTarget struct {
+	roots    []string
+	required int
+}
+
+// backupDestinations returns backupRoot followed by the entries of extra that
+// are not backupRoot itself, keeping their order.
+func backupDestinations(backupRoot string, extra []string) []string {
+	out := []string{backupRoot}
+	for _, dir := range extra {
+		if filepath.Clean(dir) != filepath.Clean(backupRoot) {
+			out = append(out, dir)
+		}
+	}
+	return out
 }
 
 func storeFirstErr(firstErr *atomic.Value, err error) {
@@ -51,7 +176,8 @@ func storeFirstErr(firstErr *atomic.Value, err error) {
 	}
 }
 
-// Worker scans configured folders, selects "old" files (based on cfg.Days),
+// Worker scans configured folders, selects "old" files (based on cfg.Days, or
+// the path's days override),
 // optionally backs them up, and then deletes them.
 //
 // High-level flow:
@@ -66,17 +192,70 @@ func storeFirstErr(firstErr *atomic.Value, err error) {
 // Safety guarantee:
 //   - A file is deleted ONLY after it is successfully backed up,
 //     unless cfg.NoBackup is true (in which case delete happens immediately).
+//   - A backed-up file is deleted ONLY after its record is synced to the dated
+//     folder's manifest.jsonl (see backupManifest).
 //
 // Stop conditions:
-// - MaxRuntime: caps total runtime of a run (best-effort).
+// - MaxRuntime: caps total runtime of a run (best-effort); it also interrupts a copy in progress.
 // - MaxFiles: caps how many *jobs are handled* by the processor (not “files deleted”).
 //
+// Plan mode (cfg.PlanFile != ""):
+//   - Walking, age filtering, and batch space accounting run exactly as in a real run.
+//   - copyFileWithRetry and DeleteFile are never called.
+//   - Every handled job is recorded in a manifest written to cfg.PlanFile (JSON + CSV).
+//
+// Several backup destinations (backupRoot plus cfg.BackupDirs):
+//   - Failover mode copies each batch to one destination and moves on to the
+//     next when the current one is unreachable or fails the space check.
+//   - Mirror mode copies every file to all usable destinations and deletes the
+//     source only once cfg.Backup.RequiredCopies of them succeeded.
+//   - Each destination gets its own manifest.jsonl records.
+//
+// Archive layout (cfg.Backup.Layout == types.LayoutArchive):
+//   - Files are streamed into one archive per configured folder and destination
+//     for the whole run instead of being copied one by one.
+//   - Their sources are only deleted after processing ends and each archive was
+//     closed, synced, renamed into place and (with verify) read back.
+//
+// Empty-directory pruning (per-path prune-empty-dirs):
+//   - Runs after all files are processed, and never in plan mode.
+//   - Only directories that lost a file during this run are candidates; their
+//     parents follow once they are removed (see dirTracker.prune).
+//
 // Counting notes:
 //   - processed is GLOBAL and increments after each job is handled by the processor
 //     (regardless of whether delete succeeded). It exists for stop conditions/reporting.
 //   - deletedByFolder is PER-FOLDER and increments ONLY when a delete succeeds.
 //   - Per-folder counts are logged AFTER all processing finishes so totals are accurate.
 func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfig, log *logging.Logger, disk diskSpaceChecker) error {
+	return runWorker(pathconfig, nil, backupRoot, cfg, log, disk)
+}
+
+// ApplyPlan executes a previously generated plan instead of rescanning.
+//
+// The plan's files are fed straight into the same batch processor Worker uses,
+// so batch space checks, retries, stop conditions, and backup-before-delete
+// ordering are unchanged. Differences from a normal run:
+//   - No folders are walked and no age filtering is performed; the plan is the
+//     reviewed list of files to process.
+//   - Each file is re-stat'ed right before it is handled, and skipped if its
+//     size or modification time changed since the plan was made, or if it no
+//     longer exists.
+//   - Entries whose config_path is not a [paths] entry in the current
+//     configuration, or whose source_path is not that path or inside it, are
+//     skipped, so a plan cannot reach outside configured paths. The plan's
+//     folder_root is ignored; it is derived from the configured path.
+//   - A file is backed up when the plan entry or the current [paths] entry asks
+//     for it, unless cfg.NoBackup forces delete-only.
+func ApplyPlan(pathconfig []types.PathConfig, plan PlanManifest, backupRoot string, cfg types.AppConfig, log *logging.Logger, disk diskSpaceChecker) error {
+	return runWorker(pathconfig, &plan, backupRoot, cfg, log, disk)
+}
+
+// runWorker is the shared implementation behind Worker and ApplyPlan.
+//
+// When plan is nil, candidate files are discovered by walking pathconfig.
+// Otherwise they are taken from plan.Files.
+func runWorker(pathconfig []types.PathConfig, plan *PlanManifest, backupRoot string, cfg types.AppConfig, log *logging.Logger, disk diskSpaceChecker) error {
 	log.Info("Starting maintenance worker")
 
 	// -------------------------------------------------------------------------
@@ -92,9 +271,24 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 	// -------------------------------------------------------------------------
 	var (
 		perFolderMu     sync.Mutex
-		deletedByFolder = make(map[string]uint64) // key: folderRoot, value: successful deletions
+		deletedByFolder = make(map[string]uint64) // key: configPath, value: successful deletions (or planned deletions)
 	)
 
+	// planMode records candidates instead of copying/deleting them.
+	// planEntries is only touched by the single processor goroutine.
+	planMode := cfg.PlanFile != ""
+	var planEntries []PlanEntry
+
+	// dirs tracks directory times and emptied directories for paths with
+	// prune-empty-dirs enabled. pruneByPath is read-only after this point.
+	dirs := newDirTracker()
+	pruneByPath := make(map[string]bool)
+	for _, pc := range pathconfig {
+		if pc.PruneEmptyDirs {
+			pruneByPath[pc.Path] = true
+		}
+	}
+
 	// -------------------------------------------------------------------------
 	// Defaults / defensive config normalization
 	//
@@ -113,12 +307,93 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 	// Track start time for MaxRuntime enforcement.
 	start := time.Now()
 
+	// runID tags every backup manifest record written by this run, so records
+	// from several runs in one dated folder can be told apart.
+	runID := newRunID(start)
+	log.Infof("Run ID: %s", runID)
+
+	// destinations are the backup roots in config order: backupRoot first, then
+	// any further cfg.BackupDirs. requiredCopies is how many of them must hold a
+	// file before its source is deleted (see selectDestinations). Only the
+	// processor goroutine moves activeDestination.
+	destinations := backupDestinations(backupRoot, cfg.BackupDirs)
+	requiredCopies := cfg.Backup.RequiredCopies(len(destinations))
+	activeDestination := 0
+	if len(destinations) > 1 {
+		mode := cfg.Backup.Mode
+		if mode == "" {
+			mode = types.BackupModeFailover
+		}
+		log.Infof("Backup destinations (%s, %d required): %s", mode, requiredCopies, strings.Join(destinations, "; "))
+	}
+
+	// manifests record each backed-up file in <root>/<DDMmmYY>/manifest.jsonl,
+	// one per destination root (including per-path dest roots), opened on first
+	// use. They are only touched by the processor goroutine and closed after it
+	// exits.
+	manifests := make(map[string]*backupManifest)
+	manifestFor := func(root string) *backupManifest {
+		m, ok := manifests[root]
+		if !ok {
+			m = newBackupManifest(root, runID)
+			manifests[root] = m
+		}
+		return m
+	}
+
+	// archiveMode bundles backups into archives (layout=archive). archives
+	// holds the open archive per destination root and folder, bundles every
+	// archive opened in order, and archived the files waiting for their
+	// archives to be finished before they are deleted (see finishArchives).
+	// All are only touched by the processor goroutine until it exits.
+	type archiveKey struct{ root, folderRoot string }
+	type archivedJob struct {
+		job      FileJob
+		size     int64
+		modTime  time.Time
+		bundles  []*archiveBundle
+		required int
+	}
+	archiveMode := cfg.Backup.Layout == types.LayoutArchive
+	archiveFormat := cfg.Backup.Archive
+	if archiveFormat == "" {
+		archiveFormat = types.ArchiveZip
+	}
+	archives := make(map[archiveKey]*archiveBundle)
+	archiveNames := make(map[string]bool)
+	var (
+		bundles  []*archiveBundle
+		archived []archivedJob
+	)
+
+	// thr paces copies, moves and deletes ([advanced] max-bandwidth,
+	// max-ops-per-second, full-speed). nil when no limit is set.
+	thr := newThrottle(cfg.Throttle)
+	if thr != nil {
+		log.Infof(
+			"Throttle: max-bandwidth=%d B/s max-ops-per-second=%d full-speed windows=%d",
+			cfg.Throttle.MaxBandwidth,
+			cfg.Throttle.MaxOpsPerSecond,
+			len(cfg.Throttle.FullSpeed),
+		)
+	}
+
 	// ctx cancels both walkers and the processor.
 	//
 	// Cancel triggers:
 	// - a hard walker failure
 	// - stop conditions met (max runtime / max files)
-	ctx, cancel := context.WithCancel(context.Background())
+	// - the MaxRuntime deadline, which also stops a copy in progress between
+	//   chunks, so one large file cannot keep the run going long past it
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if cfg.MaxRuntime > 0 {
+		ctx, cancel = context.WithDeadline(context.Background(), start.Add(cfg.MaxRuntime))
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
 	defer cancel()
 
 	// jobInput feeds candidate files from the folder walkers into the batcher.
@@ -162,55 +437,502 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 		return false
 	}
 
-	ensureBackupSpace := func(requiredBytes uint64, reason string) bool {
+	// checkBackupSpace validates one destination for a batch. It only reports;
+	// selectDestinations decides whether a failure is fatal.
+	checkBackupSpace := func(root string, requiredBytes uint64, reason string) error {
 		if requiredBytes == 0 {
-			return true
+			// Only empty files to back up: nothing to reserve.
+			return nil
 		}
 
-		availableBytes, err := disk.AvailableBytes(backupRoot)
+		availableBytes, err := disk.AvailableBytes(root)
 		if err != nil {
-			err := fmt.Errorf("unable to check available backup space for %s: %w", backupRoot, err)
-			log.Errorf("%v", err)
-			storeFirstErr(&firstErr, err)
-			cancel()
-			return false
+			return fmt.Errorf("unable to check available backup space for %s: %w", root, err)
 		}
 
 		if availableBytes < requiredBytes {
-			err := fmt.Errorf(
+			return fmt.Errorf(
 				"insufficient backup space for %s: required=%d bytes available=%d bytes backupRoot=%s",
 				reason,
 				requiredBytes,
 				availableBytes,
-				backupRoot,
+				root,
+			)
+		}
+
+		// [backup] min-free / min-free-percent: the reserve must still be free
+		// after the whole batch is copied, so a run never fills a shared volume.
+		var totalBytes uint64
+		if cfg.Backup.MinFreePercent > 0 {
+			totalBytes, err = disk.TotalBytes(root)
+			if err != nil {
+				return fmt.Errorf("unable to check backup volume size for %s: %w", root, err)
+			}
+		}
+		reserve, reserveDesc := backupReserve(cfg.Backup, totalBytes)
+		if remaining := availableBytes - requiredBytes; remaining < reserve {
+			return fmt.Errorf(
+				"backup space reserve would be violated for %s: %s must stay free, but only %d bytes would remain (required=%d bytes available=%d bytes backupRoot=%s)",
+				reason,
+				reserveDesc,
+				remaining,
+				requiredBytes,
+				availableBytes,
+				root,
 			)
-			log.Errorf("%v", err)
-			storeFirstErr(&firstErr, err)
-			cancel()
-			return false
 		}
 
 		log.Debugf(
-			"Backup space check OK for %s: required=%d bytes available=%d bytes",
+			"Backup space check OK for %s: required=%d bytes available=%d bytes backupRoot=%s",
 			reason,
 			requiredBytes,
 			availableBytes,
+			root,
 		)
 
-		return true
+		return nil
 	}
 
-	backupBytesForBatch := func(batch []FileJob) uint64 {
-		var requiredBytes uint64
+	// selectDestinations picks the destinations one destination group of a batch
+	// is copied to and how many of those copies each file needs before its
+	// source may be deleted. dest is the group's per-path root, or "" for the
+	// [backup] destinations.
+	//
+	//   - Per-path dest: it must pass the space check; there is no fallback,
+	//     because such data must not land anywhere else.
+	//   - One destination: it must pass the space check, as before.
+	//   - Failover: the active destination is used while it stays reachable and
+	//     has room; otherwise the next one takes over for the rest of the run.
+	//   - Mirror: every destination that is reachable and has room is used, and
+	//     the batch is refused when fewer than the quorum remain.
+	//
+	// With several destinations each one is also re-checked with CheckBackupPath,
+	// since a share can drop in the middle of a run. A single destination was
+	// already checked by the caller before the run started.
+	//
+	// Returning ok=false cancels the run before the batch is touched.
+	selectDestinations := func(dest string, requiredBytes uint64, reason string) (batchTarget, bool) {
+		fail := func(err error) (batchTarget, bool) {
+			log.Errorf("%v", err)
+			storeFirstErr(&firstErr, err)
+			cancel()
+			return batchTarget{}, false
+		}
+
+		if dest != "" {
+			if err := checkBackupSpace(dest, requiredBytes, reason); err != nil {
+				return fail(err)
+			}
+			return batchTarget{roots: []string{dest}, required: 1}, true
+		}
+		usable := func(root string) error {
+			if len(destinations) > 1 && !CheckBackupPath(root) {
+				return fmt.Errorf("backup path not accessible: %s", root)
+			}
+			return checkBackupSpace(root, requiredBytes, reason)
+		}
+
+		if len(destinations) == 1 {
+			if err := usable(destinations[0]); err != nil {
+				return fail(err)
+			}
+			return batchTarget{roots: destinations, required: 1}, true
+		}
+
+		var lastErr error
+		if cfg.Backup.Mode == types.BackupModeMirror {
+			var healthy []string
+			for _, root := range destinations {
+				if err := usable(root); err != nil {
+					log.Warnf("Mirror destination skipped for %s: %v", reason, err)
+					lastErr = err
+					continue
+				}
+				healthy = append(healthy, root)
+			}
+			if len(healthy) < requiredCopies {
+				return fail(fmt.Errorf(
+					"only %d of %d mirror destination(s) usable for %s, quorum is %d: %w",
+					len(healthy),
+					len(destinations),
+					reason,
+					requiredCopies,
+					lastErr,
+				))
+			}
+			return batchTarget{roots: healthy, required: requiredCopies}, true
+		}
+
+		for i := activeDestination; i < len(destinations); i++ {
+			if err := usable(destinations[i]); err != nil {
+				log.Warnf("Backup destination unusable for %s: %v", reason, err)
+				lastErr = err
+				continue
+			}
+			if i != activeDestination {
+				log.Warnf("Failing over to backup destination %s for %s", destinations[i], reason)
+				activeDestination = i
+			}
+			return batchTarget{roots: destinations[i : i+1], required: 1}, true
+		}
+		return fail(fmt.Errorf("no usable backup destination for %s: %w", reason, lastErr))
+	}
+
+	// backupBytesByDest totals the backup-enabled file sizes of a batch per
+	// destination group (job.dest, "" for the [backup] destinations). A group
+	// is present, possibly with 0 bytes, whenever it has a job to back up.
+	// Compressed backups count at their estimated size (estimatedBackupBytes),
+	// encrypted ones with their header and per-chunk overhead (encryptedSize).
+	backupBytesByDest := func(batch []FileJob) map[string]uint64 {
+		requiredByDest := make(map[string]uint64)
 		for _, job := range batch {
 			if job.backup {
-				requiredBytes += job.sizeBytes
+				required := estimatedBackupBytes(job.sizeBytes, job.compress, cfg.Backup.CompressRatio)
+				if cfg.Backup.Key != nil {
+					required = encryptedSize(required)
+				}
+				if archiveMode {
+					required = job.sizeBytes + archiveEntryOverhead
+				}
+				requiredByDest[job.dest] += required
+			}
+		}
+		return requiredByDest
+	}
+
+	// backupTo copies job's file into one destination root and records it in
+	// that root's manifest. It returns false, after logging why, when the copy
+	// cannot be counted as a backup.
+	//
+	// If the destination name is taken, resolveBackupTarget applies the
+	// collision policy: identical content counts as backed up, different
+	// content gets a versioned name or keeps the source. Otherwise the file is
+	// copied with retries/backoff to tolerate transient issues. With
+	// cfg.Backup.Verify, a copy only counts once its checksum matches the source;
+	// mismatches are retried like any other copy failure.
+	//
+	// A new copy gets the source's times, permissions and, on Linux as root,
+	// ownership and extended attributes (see applyMetadata). Each attribute
+	// that cannot be set is a warning, not a failed backup. A source its owner
+	// cannot read gets a readable backup, and its record keeps the exact
+	// permissions (see backupMode).
+	//
+	// In the objects layout the file goes to the object named by its checksum
+	// instead (resolveObjectTarget), and existing content is never copied again.
+	// Objects are shared by every file with the same content, so they get no
+	// per-file metadata.
+	backupTo := func(job FileJob, root string) bool {
+		opts := cfg.Backup
+		opts.Compress = job.compress
+		objects := opts.Layout == types.LayoutObjects
+
+		dstPath, err := buildBackupPath(root, job.folderRoot, job.srcPath)
+		if err != nil {
+			log.Errorf("Building backup path failed for %s: %v", job.srcPath, err)
+			return false
+		}
+
+		// Read before the source is hashed or copied, which may update its
+		// access time.
+		md, mdErr := readMetadata(job.srcPath)
+
+		var target backupTarget
+		hashCtl := copyControl{ctx: ctx, throttle: thr}
+		if objects {
+			target, err = resolveObjectTarget(job.srcPath, root, opts, hashCtl)
+		} else {
+			target, err = resolveBackupTarget(job.srcPath, dstPath, opts, hashCtl)
+		}
+		if err != nil {
+			log.Errorf("Backup skipped for %s, keeping source: %v", job.srcPath, err)
+			return false
+		}
+
+		if !objects && !target.reused && target.path != storedName(dstPath, formatOf(opts)) {
+			log.Warnf("Backup name taken by a different file, writing versioned copy: %s", target.path)
+		}
+		storedPath := target.path
+
+		sum := target.sum
+		if target.reused {
+			log.Infof("Identical backup already exists, not copying again: %s", storedPath)
+		} else {
+			sum, err = copyFileWithRetry(ctx, job.srcPath, storedPath, job.retries, opts, thr, log)
+			if err != nil {
+				log.Errorf("Backup failed for %s -> %s: %v", job.srcPath, storedPath, err)
+				return false
+			}
+			if objects && sum != target.sum {
+				// The source changed between hashing and copying, so the new
+				// object does not hold the content its name claims.
+				_ = os.Remove(storedPath)
+				log.Errorf("Source changed while it was backed up, keeping source: %s", job.srcPath)
+				return false
+			}
+			if !objects {
+				if mdErr != nil {
+					log.Warnf("Could not read metadata of %s: %v", job.srcPath, mdErr)
+				} else {
+					backupMd := md
+					backupMd.mode = backupMode(md.mode)
+					for _, err := range applyMetadata(storedPath, backupMd) {
+						log.Warnf("Could not preserve %v on backup %s", err, storedPath)
+					}
+				}
+			}
+			if cfg.Backup.Verify {
+				log.Successf("Backed up (copied and verified): %s -> %s", job.srcPath, storedPath)
+			} else {
+				log.Successf("Backed up (copied): %s -> %s", job.srcPath, storedPath)
+			}
+			log.Debugf("Checksum %s: %s", hashName(cfg.Backup.Hash), sum)
+		}
+
+		// Record where the file went before the source is deleted. Without
+		// a record the file would be hard to audit or restore, so a failed
+		// write keeps the source; the next run finds the identical backup
+		// and records it then.
+		record := BackupRecord{
+			SourcePath:    job.srcPath,
+			BackupPath:    storedPath,
+			SizeBytes:     job.sizeBytes,
+			SourceModTime: job.modTime,
+			Hash:          sum,
+			HashAlgorithm: hashName(cfg.Backup.Hash),
+			ConfigPath:    job.configPath,
+			FolderRoot:    job.folderRoot,
+			Reused:        target.reused,
+		}
+		if !objects && mdErr == nil && backupMode(md.mode) != md.mode {
+			record.SourceMode = formatMode(md.mode)
+		}
+		if isCompressed(opts.Compress) {
+			record.Compression = string(opts.Compress)
+		}
+		if opts.Key != nil {
+			record.Encryption = encAlgorithm
+			record.KeyID = opts.Key.ID
+		}
+		if objects {
+			// The logical dated path puts the record in today's manifest and
+			// gives restore the usual <folder-name>/<relative-path> layout.
+			record.BackupPath = dstPath
+			record.Object = objectRelPath(sum, formatOf(opts))
+		}
+		if err := manifestFor(root).append(record); err != nil {
+			log.Errorf("Backup manifest write failed for %s, keeping source: %v", job.srcPath, err)
+			return false
+		}
+
+		return true
+	}
+
+	// archiveTo adds job's file to the run's archive for its folder in one
+	// destination root, opening the archive on first use. It returns false,
+	// after logging why, when the file could not be added.
+	//
+	// Unlike backupTo there are no retries: a failed write can leave the
+	// archive stream inconsistent, so that archive is abandoned (see
+	// archiveBundle.broken) and the next file starts a new one.
+	archiveTo := func(job FileJob, root string) (*archiveBundle, archivedFile, bool) {
+		key := archiveKey{root: root, folderRoot: job.folderRoot}
+		b := archives[key]
+		if b == nil || b.broken != nil {
+			p, err := archiveBundlePath(root, job.folderRoot, archiveFormat, archiveNames)
+			if err == nil {
+				b, err = openArchiveBundle(root, p, archiveFormat, cfg.Backup.CompressLevel)
+			}
+			if err != nil {
+				log.Errorf("Could not create archive for %s in %s, keeping source: %v", job.srcPath, root, err)
+				return nil, archivedFile{}, false
+			}
+			log.Infof("Writing archive: %s", p)
+			archives[key] = b
+			archiveNames[p] = true
+			bundles = append(bundles, b)
+		}
+
+		member, err := b.add(job, cfg.Backup.Hash, copyControl{throttle: thr})
+		if err != nil {
+			log.Errorf("Archiving failed for %s -> %s, keeping source: %v", job.srcPath, b.path, err)
+			return nil, archivedFile{}, false
+		}
+		log.Successf("Archived: %s -> %s (%s)", job.srcPath, b.path, member.name)
+		return b, member, true
+	}
+
+	// moveTo renames job's file into root's dated layout when both are on the
+	// same filesystem ([backup] move=yes), so no bytes are copied and the
+	// rename itself removes the source. It returns false when the file has to
+	// be copied instead:
+	//   - the backup is not a plain copy in the dated layout;
+	//   - the source and root are on different filesystems;
+	//   - a backup with that name exists (copying applies the collision policy);
+	//   - the rename or the manifest write failed.
+	//
+	// A moved file keeps all of its metadata. Its content is never read, so
+	// its manifest record has no checksum and is marked moved.
+	moveTo := func(job FileJob, root string) bool {
+		plainDated := cfg.Backup.Layout == "" || cfg.Backup.Layout == types.LayoutDated
+		if !cfg.Backup.Move || !plainDated || isCompressed(job.compress) || cfg.Backup.Key != nil {
+			return false
+		}
+		if same, err := sameDevice(job.srcPath, root); err != nil || !same {
+			return false
+		}
+
+		dstPath, err := buildBackupPath(root, job.folderRoot, job.srcPath)
+		if err != nil || DoesFileExist(dstPath) {
+			return false
+		}
+		createdDirs, err := mkdirAllCreated(filepath.Dir(dstPath))
+		if err != nil {
+			log.Warnf("Could not create backup folder for %s, copying instead: %v", job.srcPath, err)
+			return false
+		}
+		if err := thr.wait(ctx, 0, 1); err != nil {
+			return false
+		}
+		if err := os.Rename(job.srcPath, dstPath); err != nil {
+			log.Warnf("Move failed for %s, copying instead: %v", job.srcPath, err)
+			return false
+		}
+		if durable(cfg.Backup) {
+			// Both folders changed; the file is safe once both are on disk,
+			// along with any backup folders created for it.
+			for _, dir := range []string{filepath.Dir(dstPath), filepath.Dir(job.srcPath)} {
+				if err := syncDir(dir); err != nil {
+					log.Warnf("Could not sync folder %s after moving %s: %v", dir, job.srcPath, err)
+				}
+			}
+			if err := syncCreatedDirs(createdDirs); err != nil {
+				log.Warnf("Could not sync new backup folders after moving %s: %v", job.srcPath, err)
+			}
+		}
+
+		record := BackupRecord{
+			SourcePath:    job.srcPath,
+			BackupPath:    dstPath,
+			SizeBytes:     job.sizeBytes,
+			SourceModTime: job.modTime,
+			HashAlgorithm: hashName(cfg.Backup.Hash),
+			ConfigPath:    job.configPath,
+			FolderRoot:    job.folderRoot,
+			Moved:         true,
+		}
+		if err := manifestFor(root).append(record); err != nil {
+			// Without a record the file would be hard to audit or restore,
+			// so put it back, as a failed record keeps the source after a
+			// copy.
+			if backErr := os.Rename(dstPath, job.srcPath); backErr != nil {
+				log.Errorf("Backup manifest write failed for %s, and it could not be moved back from %s: %v", job.srcPath, dstPath, backErr)
+				return true
+			}
+			log.Errorf("Backup manifest write failed for %s, moved it back: %v", job.srcPath, err)
+			return false
+		}
+
+		log.Successf("Backed up (moved, same filesystem): %s -> %s", job.srcPath, dstPath)
+		return true
+	}
+
+	// sourceRemoved counts a file that is no longer at its source, after a
+	// delete or a move.
+	sourceRemoved := func(job FileJob) {
+		// Per-folder counting:
+		// Increment only on successful delete so the count reflects reality.
+		perFolderMu.Lock()
+		deletedByFolder[job.configPath]++
+		perFolderMu.Unlock()
+
+		if pruneByPath[job.configPath] {
+			dirs.markEmptied(job.configPath, filepath.Dir(job.srcPath))
+		}
+	}
+
+	// deleteSource deletes a backed-up (or backup-disabled) file and counts it.
+	deleteSource := func(job FileJob) {
+		// Not cut short by ctx: the backup is complete, and archived files are
+		// deleted after the processor stopped.
+		_ = thr.wait(context.Background(), 0, 1)
+		if err := DeleteFile(job.srcPath); err != nil {
+			log.Errorf("Delete failed for %s: %v", job.srcPath, err)
+			return
+		}
+		log.Successf("Deleted: %s", job.srcPath)
+		sourceRemoved(job)
+	}
+
+	// finishArchives completes every archive written by the run, records its
+	// files in the manifests, and only then deletes the sources that made it
+	// into at least `required` finished archives and have not changed since.
+	// It runs after the processor exited, also after a hard error: files
+	// already archived are as safe as in any other run.
+	finishArchives := func() {
+		done := make(map[*archiveBundle]map[string]bool, len(bundles))
+		for _, b := range bundles {
+			members, err := b.finish(cfg.Backup.Verify, cfg.Backup.Hash)
+			if err != nil {
+				log.Errorf("Archive %s could not be completed: %v", b.path, err)
+			}
+			if len(members) == 0 {
+				continue
+			}
+			log.Successf("Archive written: %s (%d file(s))", b.path, len(members))
+
+			recorded := make(map[string]bool, len(members))
+			for _, m := range members {
+				record := BackupRecord{
+					SourcePath:    m.job.srcPath,
+					BackupPath:    b.path,
+					SizeBytes:     uint64(m.size),
+					SourceModTime: m.modTime,
+					Hash:          m.sum,
+					HashAlgorithm: hashName(cfg.Backup.Hash),
+					ConfigPath:    m.job.configPath,
+					FolderRoot:    m.job.folderRoot,
+					Archive:       string(b.format),
+					Member:        m.name,
+				}
+				if err := manifestFor(b.root).append(record); err != nil {
+					log.Errorf("Backup manifest write failed for %s, keeping source: %v", m.job.srcPath, err)
+					continue
+				}
+				recorded[m.job.srcPath] = true
+			}
+			done[b] = recorded
+		}
+
+		for _, a := range archived {
+			copies := 0
+			for _, b := range a.bundles {
+				if done[b][a.job.srcPath] {
+					copies++
+				}
 			}
+			if copies < a.required {
+				log.Errorf("Only %d of %d required archive copies completed for %s, keeping source", copies, a.required, a.job.srcPath)
+				continue
+			}
+
+			// The archives hold the file as it was read; keep it if it
+			// changed since.
+			info, err := os.Stat(a.job.srcPath)
+			if err != nil {
+				log.Warnf("Archived file no longer accessible, not deleting: %s (%v)", a.job.srcPath, err)
+				continue
+			}
+			if info.Size() != a.size || !info.ModTime().Equal(a.modTime) {
+				log.Warnf("Archived file changed since it was archived, keeping source: %s", a.job.srcPath)
+				continue
+			}
+			deleteSource(a.job)
 		}
-		return requiredBytes
 	}
 
-	processJob := func(job FileJob) bool {
+	// processJob handles one file. targets comes from selectDestinations for
+	// the file's batch, keyed by destination group (see FileJob.dest).
+	processJob := func(job FileJob, targets map[string]batchTarget) bool {
 		if ctx.Err() != nil {
 			return false
 		}
@@ -222,47 +944,126 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 			return false
 		}
 
-		// Build destination path:
-		// backupRoot/<DDMmmYY>/<relative folder structure>/<filename>
-		dstPath, err := buildBackupPath(backupRoot, job.folderRoot, job.srcPath)
-		if err != nil {
-			log.Errorf("Building backup path failed for %s: %v", job.srcPath, err)
+		// Plan execution: the file must still be exactly what was reviewed.
+		if job.planned {
+			info, err := os.Stat(job.srcPath)
+			if err != nil {
+				log.Warnf("Skipping planned file, no longer accessible: %s (%v)", job.srcPath, err)
+				atomic.AddUint64(&processed, 1)
+				return true
+			}
+			if uint64(info.Size()) != job.sizeBytes || !info.ModTime().Equal(job.modTime) {
+				log.Warnf(
+					"Skipping planned file, changed since plan was made: %s (size %d -> %d, mtime %s -> %s)",
+					job.srcPath,
+					job.sizeBytes,
+					info.Size(),
+					job.modTime.Format(time.RFC3339),
+					info.ModTime().Format(time.RFC3339),
+				)
+				atomic.AddUint64(&processed, 1)
+				return true
+			}
+		}
+
+		// Plan mode: record what a real run would do, then move on.
+		// No copy, delete, or cooldown happens for planned jobs.
+		if planMode {
+			entry := PlanEntry{
+				SourcePath: job.srcPath,
+				SizeBytes:  job.sizeBytes,
+				ModTime:    job.modTime,
+				ConfigPath: job.configPath,
+				FolderRoot: job.folderRoot,
+				Backup:     job.backup,
+			}
+			switch {
+			case job.backup && archiveMode:
+				// backupRoot/<DDMmmYY>/<folder-name>.<ext>; the run may still
+				// pick a versioned name if this one is taken.
+				root := targets[job.dest].roots[0]
+				entry.BackupPath = filepath.Join(root, time.Now().Format(backupDateLayout), filepath.Base(job.folderRoot)+archiveExt(archiveFormat))
+			case job.backup:
+				// backupRoot/<DDMmmYY>/<relative folder structure>/<filename>
+				// on the first destination the batch would be copied to.
+				dstPath, err := buildBackupPath(targets[job.dest].roots[0], job.folderRoot, job.srcPath)
+				if err != nil {
+					log.Errorf("Building backup path failed for %s: %v", job.srcPath, err)
+					atomic.AddUint64(&processed, 1)
+					return true
+				}
+				entry.BackupPath = storedName(dstPath, storedFormat{compress: job.compress, key: cfg.Backup.Key})
+			}
+			planEntries = append(planEntries, entry)
+			log.Debugf("Planned for deletion: %s", job.srcPath)
+
+			perFolderMu.Lock()
+			deletedByFolder[job.configPath]++
+			perFolderMu.Unlock()
+
 			atomic.AddUint64(&processed, 1)
 			return true
 		}
 
 		// Backup phase (unless disabled for this path):
-		// - If destination already exists, skip backup to avoid overwriting.
-		// - Otherwise copy with retries/backoff to tolerate transient issues.
+		// - Copy into every destination selected for this batch (one, unless
+		//   mirroring), see backupTo.
+		// - The source is kept unless at least `required` copies succeeded.
+		//
+		// In the archive layout the file is added to its archives now and
+		// deleted by finishArchives once they are complete.
+		//
+		// With a single destination on the source's filesystem, the file is
+		// moved there instead of being copied and deleted (see moveTo).
 		//
 		// Important: disk-space validation is intentionally NOT done here.
 		// The worker validates backup space once per batch before this function runs.
-		if job.backup {
-			if DoesFileExist(dstPath) {
-				log.Warnf("File already exists in backup, skipping: %s", dstPath)
+		moved := false
+		if job.backup && !archiveMode && len(targets[job.dest].roots) == 1 {
+			moved = moveTo(job, targets[job.dest].roots[0])
+		}
+
+		if job.backup && archiveMode {
+			target := targets[job.dest]
+			pending := archivedJob{job: job, required: target.required}
+			for _, root := range target.roots {
+				if b, member, ok := archiveTo(job, root); ok {
+					pending.bundles = append(pending.bundles, b)
+					pending.size, pending.modTime = member.size, member.modTime
+				}
+			}
+			if len(pending.bundles) < target.required || len(pending.bundles) == 0 {
+				if len(target.roots) > 1 {
+					log.Errorf("Only %d of %d required archive copies succeeded for %s, keeping source", len(pending.bundles), target.required, job.srcPath)
+				}
 			} else {
-				if err := copyFileWithRetry(ctx, job.srcPath, dstPath, cfg.Retries, log); err != nil {
-					log.Errorf("Backup failed for %s -> %s: %v", job.srcPath, dstPath, err)
-					atomic.AddUint64(&processed, 1)
-					return true // do NOT delete if backup failed
+				archived = append(archived, pending)
+			}
+		} else if job.backup && !moved {
+			target := targets[job.dest]
+			copies := 0
+			for _, root := range target.roots {
+				if backupTo(job, root) {
+					copies++
+				}
+			}
+			if copies < target.required || copies == 0 {
+				if len(target.roots) > 1 {
+					log.Errorf("Only %d of %d required backup copies succeeded for %s, keeping source", copies, target.required, job.srcPath)
 				}
-				log.Successf("Backed up: %s -> %s", job.srcPath, dstPath)
+				atomic.AddUint64(&processed, 1)
+				return true // do NOT delete without the required backups
 			}
 		}
 
 		// Delete phase:
 		// - Only delete after successful backup (or immediately if backup is disabled).
 		// - This ordering is the main safety guarantee of the worker.
-		if err := DeleteFile(job.srcPath); err != nil {
-			log.Errorf("Delete failed for %s: %v", job.srcPath, err)
-		} else {
-			log.Successf("Deleted: %s", job.srcPath)
-
-			// Per-folder counting:
-			// Increment only on successful delete so the count reflects reality.
-			perFolderMu.Lock()
-			deletedByFolder[job.folderRoot]++
-			perFolderMu.Unlock()
+		switch {
+		case moved:
+			sourceRemoved(job)
+		case !job.backup || !archiveMode:
+			deleteSource(job)
 		}
 
 		// Global processed count for stop conditions and run reporting.
@@ -271,11 +1072,11 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 		// Optional throttle:
 		// - Reduces burst load on SMB/network shares
 		// - Helps keep the machine responsive during scheduled runs
-		if cfg.Cooldown > 0 {
+		if job.cooldown > 0 {
 			select {
 			case <-ctx.Done():
 				return false
-			case <-time.After(cfg.Cooldown):
+			case <-time.After(job.cooldown):
 			}
 		}
 
@@ -287,12 +1088,28 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 			return true
 		}
 
-		requiredBytes := backupBytesForBatch(batch)
-		if requiredBytes > 0 {
+		// Each destination group gets its own space check, in a stable order:
+		// the [backup] destinations first, then per-path roots by name.
+		requiredByDest := backupBytesByDest(batch)
+		groups := make([]string, 0, len(requiredByDest))
+		var requiredBytes uint64
+		for dest, bytes := range requiredByDest {
+			groups = append(groups, dest)
+			requiredBytes += bytes
+		}
+		sort.Strings(groups)
+
+		targets := make(map[string]batchTarget, len(groups))
+		for _, dest := range groups {
 			reason := fmt.Sprintf("batch %d (%d queued file(s))", batchNumber, len(batch))
-			if !ensureBackupSpace(requiredBytes, reason) {
+			if dest != "" {
+				reason = fmt.Sprintf("batch %d (%d queued file(s), dest %s)", batchNumber, len(batch), dest)
+			}
+			target, ok := selectDestinations(dest, requiredByDest[dest], reason)
+			if !ok {
 				return false
 			}
+			targets[dest] = target
 		}
 
 		log.Debugf(
@@ -302,12 +1119,25 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 			requiredBytes,
 		)
 
+		// fsync time is reported separately, since durability=fsync can
+		// dominate a batch of small files on slow disks.
+		batchStart := time.Now()
+		fsyncBefore := fsyncTime.Load()
+
 		for _, job := range batch {
-			if !processJob(job) {
+			if !processJob(job, targets) {
 				return false
 			}
 		}
 
+		log.Debugf(
+			"Finished batch %d: jobs=%d elapsed=%s fsync=%s",
+			batchNumber,
+			len(batch),
+			time.Since(batchStart).Round(time.Millisecond),
+			time.Duration(fsyncTime.Load()-fsyncBefore).Round(time.Millisecond),
+		)
+
 		return true
 	}
 
@@ -383,7 +1213,81 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 	sem := make(chan struct{}, cfg.Walkers)
 	var walkWG sync.WaitGroup
 
-	for _, pathConfig := range pathconfig {
+	// -------------------------------------------------------------------------
+	// Plan feeder (ApplyPlan only)
+	//
+	// A reviewed plan replaces discovery entirely: no folders are walked, and the
+	// planned files are enqueued in plan order.
+	// -------------------------------------------------------------------------
+	walkConfigs := pathconfig
+	if plan != nil {
+		walkConfigs = nil
+
+		configured := make(map[string]types.PathConfig, len(pathconfig))
+		for _, pc := range pathconfig {
+			configured[pc.Path] = pc
+		}
+		plannedByPath := make(map[string]int)
+
+		if plan.BackupRoot != "" && plan.BackupRoot != backupRoot {
+			log.Warnf("Plan was created for backup root %s; using configured backup root %s", plan.BackupRoot, backupRoot)
+		}
+		log.Infof("Applying plan created %s: %d file(s)", plan.CreatedAt.Format(time.RFC3339), len(plan.Files))
+
+		for _, entry := range plan.Files {
+			if ctx.Err() != nil || shouldStop() {
+				break
+			}
+
+			pc, ok := configured[entry.ConfigPath]
+			if !ok {
+				log.Warnf("Skipping planned file, %s is not a configured path: %s", entry.ConfigPath, entry.SourcePath)
+				continue
+			}
+
+			// The plan file may have been edited or be stale, so only its
+			// config_path is trusted: the file must be that path itself (a
+			// single-file entry) or lie inside it (a folder entry), and the
+			// folder root comes from the configuration, as a walk would set it.
+			folderRoot, ok := plannedFolderRoot(pc, entry.SourcePath)
+			if !ok {
+				log.Warnf("Skipping planned file, it is not under %s: %s", pc.Path, entry.SourcePath)
+				continue
+			}
+
+			settings := resolvePathSettings(pc, cfg)
+			if settings.maxFiles > 0 && plannedByPath[entry.ConfigPath] >= settings.maxFiles {
+				continue
+			}
+			plannedByPath[entry.ConfigPath]++
+
+			// No walk happens here, so directory times are captured from the
+			// plan's files before they are enqueued.
+			if settings.pruneEmptyDirs {
+				dirs.recordAncestors(entry.ConfigPath, filepath.Dir(entry.SourcePath))
+			}
+
+			job := FileJob{
+				srcPath:    entry.SourcePath,
+				folderRoot: folderRoot,
+				configPath: entry.ConfigPath,
+				backup:     (entry.Backup || pc.Backup) && !cfg.NoBackup,
+				sizeBytes:  entry.SizeBytes,
+				modTime:    entry.ModTime,
+				retries:    settings.retries,
+				cooldown:   settings.cooldown,
+				planned:    true,
+				dest:       pc.Dest,
+				compress:   settings.compress,
+			}
+
+			if err := enqueueJob(job); err != nil {
+				break
+			}
+		}
+	}
+
+	for _, pathConfig := range walkConfigs {
 		// Avoid launching new walkers once stop conditions are met.
 		if shouldStop() {
 			break
@@ -391,6 +1295,8 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 
 		folder := pathConfig.Path
 		backupEnabled := pathConfig.Backup
+		settings := resolvePathSettings(pathConfig, cfg)
+		filter := newPathFilter(pathConfig.Include, pathConfig.Exclude)
 
 		// Acquire a slot (blocks if cfg.Walkers walkers already running).
 		sem <- struct{}{}
@@ -412,11 +1318,31 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 				return
 			}
 
+			// Resolve the age basis once per path. An invalid or unsupported
+			// basis skips the whole path: guessing a different timestamp could
+			// delete files that are not actually old.
+			ager, err := newAgeEvaluator(pathConfig.Age, pathConfig.AgeNamePattern)
+			if err != nil {
+				log.Errorf("Invalid age setting for %s, skipping path: %v", folder, err)
+				return
+			}
+
 			// Handle file paths directly (not directories).
 			// This allows users to specify individual files in folders.txt.
 			if !fi.IsDir() {
+				// Include/exclude patterns match the file's base name here.
+				if !filter.allowsFile(filepath.Base(folder)) {
+					log.Debugf("File is excluded by filter, skipping: %s", folder)
+					return
+				}
+
 				// Check if the file is old enough to be deleted.
-				if !IsFileOlder(fi, cfg.Days) {
+				old, err := ager.isOlder(folder, fi, settings.days)
+				if err != nil {
+					log.Errorf("Age check failed for %s: %v", folder, err)
+					return
+				}
+				if !old {
 					log.Debugf("File is not old enough, skipping: %s", folder)
 					return
 				}
@@ -428,8 +1354,14 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 				job := FileJob{
 					srcPath:    folder,
 					folderRoot: folderRoot,
+					configPath: folder,
 					backup:     backupEnabled,
 					sizeBytes:  uint64(fi.Size()),
+					modTime:    fi.ModTime(),
+					retries:    settings.retries,
+					cooldown:   settings.cooldown,
+					dest:       pathConfig.Dest,
+					compress:   settings.compress,
 				}
 
 				if err := enqueueJob(job); err != nil {
@@ -441,6 +1373,11 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 
 			log.Infof("Processing folder: %s", folder)
 
+			// queued counts jobs enqueued from this folder for the per-path
+			// max-files cap. Every enqueued job is handled by the processor
+			// unless the run stops, so capping at enqueue time is exact.
+			var queued int
+
 			// WalkDir recursively scans the folder.
 			//
 			// For each file:
@@ -456,10 +1393,24 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 				}
 
 				// Directories: keep walking, but allow early cancel/stop.
+				// Excluded directories are pruned so their subtree is never walked.
 				if d.IsDir() {
 					if ctx.Err() != nil || shouldStop() {
 						return context.Canceled
 					}
+					if path != folder && !filter.empty() {
+						if rel, err := filepath.Rel(folder, path); err == nil && filter.excludesDir(rel) {
+							log.Debugf("Directory excluded by filter, skipping subtree: %s", path)
+							return fs.SkipDir
+						}
+					}
+					// WalkDir visits a directory before its files are enqueued,
+					// so this is its modification time before any deletions.
+					if settings.pruneEmptyDirs && path != folder {
+						if info, err := d.Info(); err == nil {
+							dirs.recordDir(path, info.ModTime())
+						}
+					}
 					return nil
 				}
 
@@ -468,6 +1419,15 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 					return context.Canceled
 				}
 
+				// Include/exclude filters are checked before d.Info() to avoid
+				// metadata I/O for files that are never eligible.
+				if !filter.empty() {
+					rel, err := filepath.Rel(folder, path)
+					if err != nil || !filter.allowsFile(rel) {
+						return nil
+					}
+				}
+
 				// Gather metadata to evaluate age.
 				info, err := d.Info()
 				if err != nil {
@@ -475,22 +1435,41 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 					return nil
 				}
 
-				// Skip files that are not older than cfg.Days.
-				if !IsFileOlder(info, cfg.Days) {
+				// Skip files that are not older than the path's retention days,
+				// measured with the path's age basis.
+				old, err := ager.isOlder(path, info, settings.days)
+				if err != nil {
+					log.Errorf("Age check failed for %s: %v", path, err)
+					return nil
+				}
+				if !old {
 					return nil
 				}
 
+				// Per-path max-files reached: stop walking this folder only.
+				if settings.maxFiles > 0 && queued >= settings.maxFiles {
+					log.Infof("Max files (%d) reached for %s, stopping walk", settings.maxFiles, folder)
+					return fs.SkipAll
+				}
+
 				// Enqueue work for the processor (blocks if queue is full).
 				job := FileJob{
 					srcPath:    path,
 					folderRoot: folder,
+					configPath: folder,
 					backup:     backupEnabled,
 					sizeBytes:  uint64(info.Size()),
+					modTime:    info.ModTime(),
+					retries:    settings.retries,
+					cooldown:   settings.cooldown,
+					dest:       pathConfig.Dest,
+					compress:   settings.compress,
 				}
 
 				if err := enqueueJob(job); err != nil {
 					return err
 				}
+				queued++
 
 				return nil
 			})
@@ -515,16 +1494,45 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 	// 1) wait for walkers to finish producing jobs
 	// 2) close job input channel (signals processor to flush the final batch)
 	// 3) wait for processor to finish
-	// 4) log final per-folder deletion counts (now accurate)
+	// 4) finish archives and delete their sources (layout=archive)
+	// 5) prune directories emptied by this run (opt-in per path)
+	// 6) log final per-folder deletion counts (now accurate)
 	// -------------------------------------------------------------------------
 	walkWG.Wait()
 	close(jobInput)
 	procWG.Wait()
 
+	if archiveMode && !planMode {
+		finishArchives()
+	}
+
+	for _, m := range manifests {
+		if err := m.close(); err != nil {
+			log.Errorf("%v", err)
+		}
+	}
+
+	// Pruning also runs after a hard error: directories already emptied are
+	// just as empty, and prune only touches directories that lost a file.
+	if !planMode {
+		for _, pathConfig := range pathconfig {
+			if !pruneByPath[pathConfig.Path] || !pathConfig.IsDir {
+				continue
+			}
+			settings := resolvePathSettings(pathConfig, cfg)
+			removed := dirs.prune(pathConfig.Path, pathConfig.Path, settings.pruneMinAge, log)
+			log.Countf("Amount of empty directories removed from folder %s: %d", pathConfig.Path, removed)
+		}
+	}
+
 	// Accurate per-path reporting happens AFTER processing finishes.
 	perFolderMu.Lock()
 	for _, pathConfig := range pathconfig {
 		count := deletedByFolder[pathConfig.Path]
+		if planMode {
+			log.Countf("Amount of files planned for deletion from %s: %d", pathConfig.Path, count)
+			continue
+		}
 		if pathConfig.IsDir {
 			log.Countf("Amount of files deleted from folder %s: %d", pathConfig.Path, count)
 		} else {
@@ -537,10 +1545,42 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 	perFolderMu.Unlock()
 
 	// Return the first hard error (if any).
+	//
+	// In plan mode no manifest is written in this case: a partial plan could be
+	// mistaken for a complete one during review.
 	if v := firstErr.Load(); v != nil {
 		return v.(error)
 	}
 
+	if planMode {
+		manifest := PlanManifest{
+			CreatedAt:  time.Now(),
+			BackupRoot: backupRoot,
+			Days:       cfg.Days,
+			Files:      planEntries,
+		}
+		for _, e := range planEntries {
+			manifest.TotalBytes += e.SizeBytes
+			if e.Backup {
+				manifest.BackupBytes += e.SizeBytes
+			}
+		}
+
+		if err := writePlanManifest(cfg.PlanFile, manifest); err != nil {
+			return err
+		}
+
+		jsonPath, csvPath := planOutputPaths(cfg.PlanFile)
+		log.Countf(
+			"Plan written: files=%d totalBytes=%d backupBytes=%d json=%s csv=%s",
+			len(planEntries),
+			manifest.TotalBytes,
+			manifest.BackupBytes,
+			jsonPath,
+			csvPath,
+		)
+	}
+
 	// End-of-run reporting: helps diagnose why scheduled runs ended early.
 	if cfg.MaxRuntime > 0 && time.Since(start) >= cfg.MaxRuntime {
 		log.Warnf("Stopped due to max runtime (%s). Jobs handled: %d", cfg.MaxRuntime, atomic.LoadUint64(&processed))
@@ -551,3 +1591,18 @@ func Worker(pathconfig []types.PathConfig, backupRoot string, cfg types.AppConfi
 
 	return nil
 }
+
+// plannedFolderRoot returns the folder root a walk of pc would give srcPath,
+// and false when srcPath is neither pc's file nor inside pc's folder.
+func plannedFolderRoot(pc types.PathConfig, srcPath string) (string, bool) {
+	if !pc.IsDir {
+		if filepath.Clean(srcPath) != filepath.Clean(pc.Path) {
+			return "", false
+		}
+		return filepath.Dir(pc.Path), true
+	}
+	if !isStrictlyUnder(pc.Path, srcPath) {
+		return "", false
+	}
+	return pc.Path, true
+}
@@ -203,6 +203,7 @@ func countFilesWithPrefixSuffix(t *testing.T, root, prefix, suffix string) int {
 
 type fakeDiskSpaceChecker struct {
 	availableBytes uint64
+	totalBytes     uint64 // 0 means same as availableBytes
 	err            error
 }
 
@@ -213,6 +214,16 @@ func (f fakeDiskSpaceChecker) AvailableBytes(path string) (uint64, error) {
 	return f.availableBytes, nil
 }
 
+func (f fakeDiskSpaceChecker) TotalBytes(path string) (uint64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.totalBytes == 0 {
+		return f.availableBytes, nil
+	}
+	return f.totalBytes, nil
+}
+
 func newTestDisk() fakeDiskSpaceChecker {
 	return fakeDiskSpaceChecker{
 		availableBytes: 1 << 40, // 1 TB; enough for normal tests
@@ -232,3 +243,22 @@ func (f *countingDiskSpaceChecker) AvailableBytes(path string) (uint64, error) {
 	}
 	return f.availableBytes, nil
 }
+
+func (f *countingDiskSpaceChecker) TotalBytes(path string) (uint64, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	return f.availableBytes, nil
+}
+
+// perRootDisk reports available (and total) bytes per backup root, for tests
+// with several destinations. Unknown roots report 0 bytes.
+type perRootDisk map[string]uint64
+
+func (d perRootDisk) AvailableBytes(path string) (uint64, error) {
+	return d[path], nil
+}
+
+func (d perRootDisk) TotalBytes(path string) (uint64, error) {
+	return d[path], nil
+}
@@ -3,6 +3,7 @@ package maintenance
 import (
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -347,6 +348,95 @@ func TestWorker_Integration_InsufficientBackupSpace(t *testing.T) {
 	}
 }
 
+func TestBackupReserve_Table(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     types.BackupOptions
+		total    uint64
+		want     uint64
+		wantDesc string
+	}{
+		{name: "no reserve", total: 1000, want: 0},
+		{name: "absolute", opts: types.BackupOptions{MinFree: 300}, total: 1000, want: 300, wantDesc: "min-free"},
+		{name: "percent", opts: types.BackupOptions{MinFreePercent: 10}, total: 1000, want: 100, wantDesc: "min-free-percent"},
+		{name: "larger absolute wins", opts: types.BackupOptions{MinFree: 300, MinFreePercent: 10}, total: 1000, want: 300, wantDesc: "min-free"},
+		{name: "larger percent wins", opts: types.BackupOptions{MinFree: 50, MinFreePercent: 10}, total: 1000, want: 100, wantDesc: "min-free-percent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, desc := backupReserve(tt.opts, tt.total)
+			if got != tt.want || !strings.HasPrefix(desc, tt.wantDesc) {
+				t.Fatalf("want (%d, %s...), got (%d, %q)", tt.want, tt.wantDesc, got, desc)
+			}
+		})
+	}
+}
+
+func TestWorker_Integration_BackupSpaceReserve(t *testing.T) {
+	const payload = "0123456789" // 10 bytes
+
+	tests := []struct {
+		name     string
+		opts     types.BackupOptions
+		disk     fakeDiskSpaceChecker
+		wantCopy bool
+		wantErr  string
+	}{
+		{
+			name:     "reserve still free after batch",
+			opts:     types.BackupOptions{MinFree: 90},
+			disk:     fakeDiskSpaceChecker{availableBytes: 100},
+			wantCopy: true,
+		},
+		{
+			name:    "min-free violated",
+			opts:    types.BackupOptions{MinFree: 91},
+			disk:    fakeDiskSpaceChecker{availableBytes: 100},
+			wantErr: "min-free=91 bytes",
+		},
+		{
+			name:    "min-free-percent violated",
+			opts:    types.BackupOptions{MinFreePercent: 10},
+			disk:    fakeDiskSpaceChecker{availableBytes: 100, totalBytes: 1000},
+			wantErr: "min-free-percent=10%",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, src, backup := newSandbox(t)
+			cfg, log := newTestCfgAndLogger(t, root)
+			cfg.Days = 5
+			cfg.Retries = 0
+			cfg.Backup = tt.opts
+
+			target := filepath.Join(src, "a.txt")
+			mustWriteFile(t, target, payload)
+			mustSetAgeDays(t, target, 10)
+
+			pathconfig := []types.PathConfig{{Path: src, Backup: true, IsDir: true}}
+			err := Worker(pathconfig, backup, cfg, log, tt.disk)
+
+			if !tt.wantCopy {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error mentioning %q, got %v", tt.wantErr, err)
+				}
+				assertExists(t, target)
+				if countBackupsWithBase(t, backup, "a.txt") > 0 {
+					t.Fatalf("expected no backup when the reserve would be violated")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("worker error: %v", err)
+			}
+			assertNotExists(t, target)
+		})
+	}
+}
+
 func TestWorker_Integration_NoBackup_DoesNotRequireBackupSpace(t *testing.T) {
 	root, src, backup := newSandbox(t)
 	cfg, log := newTestCfgAndLogger(t, root)
@@ -469,3 +559,46 @@ func TestWorker_Integration_InsufficientBackupSpaceForBatchKeepsSources(t *testi
 		t.Fatalf("expected no backups to be written, got %d", got)
 	}
 }
+
+func TestWorker_Integration_PerPathOverrides(t *testing.T) {
+	root := t.TempDir()
+
+	longRetention := filepath.Join(root, "long")
+	capped := filepath.Join(root, "capped")
+	backup := filepath.Join(root, "backup")
+	mustMkdirAll(t, longRetention)
+	mustMkdirAll(t, capped)
+	mustMkdirAll(t, backup)
+
+	cfg, log := newTestCfgAndLogger(t, root)
+	cfg.Days = 5
+
+	// 10 days old: expired under the global 5 days, kept under a 30-day override.
+	kept := filepath.Join(longRetention, "kept.txt")
+	mustWriteFile(t, kept, "keep")
+	mustSetAgeDays(t, kept, 10)
+
+	totalCapped := 4
+	for i := 0; i < totalCapped; i++ {
+		p := filepath.Join(capped, "c"+strconv.Itoa(i)+".txt")
+		mustWriteFile(t, p, "x")
+		mustSetAgeDays(t, p, 10)
+	}
+
+	days := 30
+	maxFiles := 2
+	pathconfig := []types.PathConfig{
+		{Path: longRetention, Backup: false, IsDir: true, Days: &days},
+		{Path: capped, Backup: false, IsDir: true, MaxFiles: &maxFiles},
+	}
+
+	if err := Worker(pathconfig, backup, cfg, log, newTestDisk()); err != nil {
+		t.Fatalf("worker error: %v", err)
+	}
+
+	assertExists(t, kept)
+
+	if remaining := countNonDirFiles(t, capped); remaining != totalCapped-maxFiles {
+		t.Fatalf("expected %d files to remain in capped path, got %d", totalCapped-maxFiles, remaining)
+	}
+}
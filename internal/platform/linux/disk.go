@@ -0,0 +1,52 @@
+//go:build linux
+
+package linux
+
+import "golang.org/x/sys/unix"
+
+// DiskUsage mirrors windows.DiskUsage: sizes in bytes of the filesystem that
+// holds a path. Available is what an unprivileged process may still write;
+// Free also counts blocks reserved for root.
+type DiskUsage struct {
+	Total     uint64
+	Free      uint64
+	Available uint64
+}
+
+// DiskUsage reports the size of the filesystem that holds path using statfs(2).
+// For network mounts (NFS, CIFS) the values are the ones the server reports.
+func (Platform) DiskUsage(path string) (DiskUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return DiskUsage{}, err
+	}
+
+	// Block counts are in fragment-size units; fall back to Bsize like glibc's
+	// statvfs does for filesystems that leave Frsize unset.
+	blockSize := uint64(st.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(st.Bsize)
+	}
+
+	return DiskUsage{
+		Total:     st.Blocks * blockSize,
+		Free:      st.Bfree * blockSize,
+		Available: st.Bavail * blockSize,
+	}, nil
+}
+
+func (p Platform) AvailableBytes(path string) (uint64, error) {
+	usage, err := p.DiskUsage(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.Available, nil
+}
+
+func (p Platform) TotalBytes(path string) (uint64, error) {
+	usage, err := p.DiskUsage(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.Total, nil
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package linux
+
+import "testing"
+
+func TestDiskUsage_TempDir(t *testing.T) {
+	var p Platform
+
+	usage, err := p.DiskUsage(t.TempDir())
+	if err != nil {
+		t.Fatalf("DiskUsage: %v", err)
+	}
+	if usage.Total == 0 || usage.Free > usage.Total || usage.Available > usage.Free {
+		t.Fatalf("implausible usage: %+v", usage)
+	}
+
+	avail, err := p.AvailableBytes(t.TempDir())
+	if err != nil {
+		t.Fatalf("AvailableBytes: %v", err)
+	}
+	if avail > usage.Total {
+		t.Fatalf("available %d exceeds total %d", avail, usage.Total)
+	}
+
+	if _, err := p.DiskUsage("/does/not/exist"); err == nil {
+		t.Fatalf("expected error for missing path")
+	}
+}
@@ -39,7 +39,3 @@ func (Platform) EnsureConfig(configDir string, exeDir string) (bool, error) {
 	}
 	return false, err
 }
-
-func (Platform) AvailableBytes(path string) (uint64, error) {
-	return 0, fmt.Errorf("disk space check not implemented for this platform")
-}
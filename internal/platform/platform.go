@@ -18,6 +18,7 @@ import "file-maintenance/internal/types"
 // - RunSetup opens the setup/configuration experience when available.
 // - EnsureConfig verifies config.ini exists before maintenance begins.
 // - AvailableBytes returns writable bytes available at a destination path.
+// - TotalBytes returns the size of the volume that holds a destination path.
 //
 // Note: main currently chooses portable defaults (<exe>/config and <exe>/logs)
 // instead of DefaultConfigDir and DefaultLogDir, but these methods remain part of
@@ -30,4 +31,5 @@ type Platform interface {
 	EnsureConfig(configDir string, exeDir string) (bool, error)
 
 	AvailableBytes(path string) (uint64, error)
+	TotalBytes(path string) (uint64, error)
 }
@@ -4,13 +4,18 @@ package windows
 
 import "golang.org/x/sys/windows"
 
+// DiskUsage is the size in bytes of the volume that holds a path. Available is
+// what the current user may still write (after quotas); Free is the volume's
+// total free space.
 type DiskUsage struct {
 	Total     uint64
 	Free      uint64
 	Available uint64
 }
 
-func (Platform) AvailableBytes(path string) (uint64, error) {
+// DiskUsage reports the size of the volume that holds path. UNC paths to a
+// share report the share's volume.
+func (Platform) DiskUsage(path string) (DiskUsage, error) {
 	var freeBytesAvailable, totalNumberOfBytes, totalNumberOfFreeBytes uint64
 
 	err := windows.GetDiskFreeSpaceEx(
@@ -19,9 +24,29 @@ func (Platform) AvailableBytes(path string) (uint64, error) {
 		&totalNumberOfBytes,
 		&totalNumberOfFreeBytes,
 	)
+	if err != nil {
+		return DiskUsage{}, err
+	}
+
+	return DiskUsage{
+		Total:     totalNumberOfBytes,
+		Free:      totalNumberOfFreeBytes,
+		Available: freeBytesAvailable,
+	}, nil
+}
+
+func (p Platform) AvailableBytes(path string) (uint64, error) {
+	usage, err := p.DiskUsage(path)
 	if err != nil {
 		return 0, err
 	}
+	return usage.Available, nil
+}
 
-	return freeBytesAvailable, nil
+func (p Platform) TotalBytes(path string) (uint64, error) {
+	usage, err := p.DiskUsage(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.Total, nil
 }
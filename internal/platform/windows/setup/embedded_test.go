@@ -11,6 +11,8 @@ func TestEmbeddedSetupScriptLoadsExistingConfig(t *testing.T) {
 		"function Load-ExistingConfiguration",
 		"Load-ExistingConfiguration",
 		"Add-PathRow -Path $path -Backup $backupEnabled",
+		"-Options ($options -join \", \")",
+		"$script:BackupExtraLines += \"$key=$($existingConfig[\"backup\"][$key])\"",
 		"Convert-DurationValue -Value $existingConfig[\"advanced\"][\"cooldown\"] -TargetUnit \"Milliseconds\"",
 		"Convert-DurationValue -Value $existingConfig[\"advanced\"][\"max-runtime\"] -TargetUnit \"Minutes\"",
 	}
@@ -21,3 +23,30 @@ func TestEmbeddedSetupScriptLoadsExistingConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestEmbeddedSetupScriptKeepsUnknownAdvancedKeys(t *testing.T) {
+	markers := []string{
+		"$script:AdvancedExtraLines += \"$key=$($existingConfig[\"advanced\"][$key])\"",
+		"if ($script:AdvancedKeys -notcontains $key)",
+		"max-runtime=$maxRuntime$advancedExtra",
+	}
+
+	for _, marker := range markers {
+		if !strings.Contains(setupScript, marker) {
+			t.Fatalf("embedded setup script is missing %q", marker)
+		}
+	}
+}
+
+func TestEmbeddedSetupScriptValidatesEachBackupDestination(t *testing.T) {
+	markers := []string{
+		"$backupTextBox.Text.Split(';')",
+		"foreach ($backupPath in $backupPaths)",
+	}
+
+	for _, marker := range markers {
+		if !strings.Contains(setupScript, marker) {
+			t.Fatalf("embedded setup script is missing %q", marker)
+		}
+	}
+}
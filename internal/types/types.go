@@ -16,26 +16,351 @@ const (
 	SetupActionSavedAndRun
 )
 
+// AgeBasis selects which timestamp decides whether a file is old enough.
+type AgeBasis string
+
+const (
+	// AgeModified uses the modification time (default).
+	AgeModified AgeBasis = "mtime"
+	// AgeChanged uses the inode/metadata change time (Linux, macOS).
+	AgeChanged AgeBasis = "ctime"
+	// AgeAccessed uses the last access time. It may be stale when the
+	// filesystem is mounted without access-time updates.
+	AgeAccessed AgeBasis = "atime"
+	// AgeCreated uses the creation (birth) time where the OS exposes it.
+	AgeCreated AgeBasis = "birth"
+	// AgeFileName parses a date from the file name using AgeNamePattern.
+	AgeFileName AgeBasis = "name"
+)
+
 // PathConfig represents a path entry from config.ini with its associated backup setting.
 //
-// Format: path, yes|no (comma-separated)
+// Format: path, yes|no (comma-separated), optionally followed by key=value overrides:
+//
+//	C:\Temp\scans, backup=yes, days=30, retries=5, cooldown=50ms, max-files=1000
+//
 // - path: the file or folder to process
 // - backup: "yes" to enable backup, "no" to disable backup for this path
+//
+// Override fields are nil when the entry does not set them; the run-wide value
+// from [settings]/[advanced]/CLI then applies to this path.
 type PathConfig struct {
 	Path   string
 	Backup bool
 	IsDir  bool
+
+	// Days overrides the retention period for files under this path.
+	Days *int
+
+	// Retries overrides the number of copy retries for files under this path.
+	Retries *int
+
+	// Cooldown overrides the delay after each handled file under this path.
+	Cooldown *time.Duration
+
+	// MaxFiles caps how many files from this path are handled in one run.
+	// 0 means unlimited for this path (the run-wide MaxFiles still applies).
+	MaxFiles *int
+
+	// Include and Exclude are glob patterns matched against paths relative to
+	// this entry (e.g. include=*.log;*.tmp, exclude=**/keep/**;*.db).
+	// Excluded directories are pruned from the walk entirely.
+	Include []string
+	Exclude []string
+
+	// Age selects the timestamp used for retention under this path.
+	// Empty means AgeModified.
+	Age AgeBasis
+
+	// AgeNamePattern is the file name date pattern used when Age is
+	// AgeFileName, e.g. "IMG_{yyyyMMdd}". Files whose names do not match are
+	// never selected.
+	AgeNamePattern string
+
+	// PruneEmptyDirs removes directories under this folder that became empty
+	// during the run. The folder itself is never removed.
+	PruneEmptyDirs bool
+
+	// PruneMinAge is the minimum age of a directory (by its modification time
+	// before this run deleted anything) before it may be pruned. Nil means
+	// the default of 24h.
+	PruneMinAge *time.Duration
+
+	// Dest overrides the [backup] destination for this path only, e.g. to keep
+	// one department's data on its own archive share. Empty means the [backup]
+	// path (with its mode). A path with Dest never falls back to other roots.
+	Dest string
+
+	// Compress overrides [backup] compress for this path. Empty means the
+	// [backup] setting.
+	Compress Compression
+}
+
+// HashAlgorithm names the checksum used to verify backup copies.
+type HashAlgorithm string
+
+const (
+	HashSHA256 HashAlgorithm = "sha256"
+	HashSHA512 HashAlgorithm = "sha512"
+	HashSHA1   HashAlgorithm = "sha1"
+	HashMD5    HashAlgorithm = "md5"
+)
+
+// CollisionPolicy decides what happens when a backup destination name is
+// already taken by a different file.
+type CollisionPolicy string
+
+const (
+	// CollisionVersion writes the copy as "name (2).ext", "name (3).ext", ...
+	CollisionVersion CollisionPolicy = "version"
+	// CollisionKeep copies nothing and keeps the source file.
+	CollisionKeep CollisionPolicy = "keep"
+)
+
+// Compression selects how backup copies are stored.
+type Compression string
+
+const (
+	// CompressNone stores backups as plain copies.
+	CompressNone Compression = "none"
+	// CompressGzip stores backups as gzip streams named "<file>.gz".
+	CompressGzip Compression = "gzip"
+)
+
+// BackupLayout selects how backups are arranged under a backup root.
+type BackupLayout string
+
+const (
+	// LayoutDated copies each file to <root>/<DDMmmYY>/<folder-name>/<relative-path>.
+	LayoutDated BackupLayout = "dated"
+	// LayoutObjects stores each distinct content once under <root>/objects/,
+	// named by its checksum. Each dated folder keeps only manifest.jsonl, the
+	// index mapping original paths to objects.
+	LayoutObjects BackupLayout = "objects"
+	// LayoutArchive streams each path's files into one archive per run,
+	// <root>/<DDMmmYY>/<folder-name>.<ext> (see ArchiveFormat).
+	LayoutArchive BackupLayout = "archive"
+)
+
+// ArchiveFormat selects the archive type written with LayoutArchive.
+type ArchiveFormat string
+
+const (
+	// ArchiveZip writes deflate-compressed .zip files that Windows Explorer opens.
+	ArchiveZip ArchiveFormat = "zip"
+	// ArchiveTar writes uncompressed .tar files.
+	ArchiveTar ArchiveFormat = "tar"
+	// ArchiveTarGz writes gzip-compressed .tar.gz files.
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+)
+
+// Durability selects how backups are flushed to disk before the source is
+// deleted.
+type Durability string
+
+const (
+	// DurabilityFsync (the default) fsyncs each backup file before it is
+	// renamed into place, and its folder after, so a power loss right after
+	// the source is deleted cannot lose both.
+	DurabilityFsync Durability = "fsync"
+	// DurabilityNone leaves flushing to the operating system.
+	DurabilityNone Durability = "none"
+)
+
+// BackupKey is an AES-256 key loaded from the [backup] encrypt-key file.
+type BackupKey struct {
+	// ID identifies the key without revealing it: the first 8 bytes of the
+	// key's SHA-256, hex-encoded. It is stored in every encrypted backup and
+	// in the manifest so a backup can be matched to its key.
+	ID string
+
+	// Key is the 32-byte AES-256 key.
+	Key []byte
+}
+
+// BackupMode decides how several [backup] destinations are used.
+type BackupMode string
+
+const (
+	// BackupModeFailover copies each batch to one destination: the first one,
+	// or the next one when it is unreachable or out of space.
+	BackupModeFailover BackupMode = "failover"
+	// BackupModeMirror copies every file to all reachable destinations.
+	BackupModeMirror BackupMode = "mirror"
+)
+
+// BackupOptions controls how backup copies are written, from the [backup]
+// section of config.ini:
+//
+//	[backup]
+//	path=D:\backups
+//	hash=sha256
+//	verify=yes
+//	collision=version
+//	retention=90
+//	min-free=20GB
+//	min-free-percent=10
+//	mode=mirror
+//	quorum=2
+//	compress=gzip
+//	compress-level=6
+//	compress-ratio=4
+//	encrypt-key=backup.key
+//	layout=objects
+//	archive=zip
+//	move=yes
+//	durability=fsync
+type BackupOptions struct {
+	// Hash is the checksum computed from the source while it is copied.
+	// Empty means HashSHA256.
+	Hash HashAlgorithm
+
+	// Verify re-reads each copy and compares its checksum with the source
+	// before the copy is accepted. A mismatch is a failed copy, so it is
+	// retried and the source is never deleted on a bad copy.
+	Verify bool
+
+	// Collision applies when the destination name exists with different
+	// content. Identical content (same size and checksum) is always treated
+	// as already backed up. Empty means CollisionVersion.
+	Collision CollisionPolicy
+
+	// Retention removes dated backup folders (<backupRoot>/<DDMmmYY>) whose
+	// folder date is more than this many days ago. 0 keeps backups forever.
+	Retention int
+
+	// MinFree is a reserve in bytes that must still be available on the
+	// backup volume after a batch is copied. 0 disables the reserve.
+	MinFree uint64
+
+	// MinFreePercent is the same reserve as a percentage of the backup
+	// volume's total size. When both are set, the larger reserve applies.
+	MinFreePercent float64
+
+	// Mode applies when [backup] path lists several destinations.
+	// Empty means BackupModeFailover.
+	Mode BackupMode
+
+	// Quorum is how many mirror copies must succeed before a source file may
+	// be deleted. 0 means every destination. Only used with BackupModeMirror.
+	Quorum int
+
+	// Compress stores backups compressed. Empty means CompressNone. A
+	// [paths] entry may override it with compress=.
+	Compress Compression
+
+	// CompressLevel is the gzip level, 1 (fastest) to 9 (smallest).
+	// 0 means gzip's default.
+	CompressLevel int
+
+	// CompressRatio is the expected compression ratio (e.g. 4 for 4:1) used
+	// by the batch space check. 0 counts compressed backups at raw size.
+	CompressRatio float64
+
+	// EncryptKeyFile is the [backup] encrypt-key path as configured
+	// (relative paths are resolved against the config directory).
+	EncryptKeyFile string
+
+	// Key, when set, encrypts every backup with AES-256-GCM. It is loaded
+	// from EncryptKeyFile by config.ReadAllConfig.
+	Key *BackupKey
+
+	// Layout selects the backup layout. Empty means LayoutDated.
+	Layout BackupLayout
+
+	// Archive is the archive type for LayoutArchive. Empty means ArchiveZip.
+	Archive ArchiveFormat
+
+	// Move renames a file into the backup instead of copying and deleting it
+	// when the source and the backup root are on the same filesystem and the
+	// backup is a plain copy in the dated layout.
+	Move bool
+
+	// Durability selects how backups are flushed. Empty means DurabilityFsync.
+	Durability Durability
+}
+
+// RequiredCopies returns how many successful backup copies a file needs
+// before its source may be deleted, given the number of destinations.
+func (o BackupOptions) RequiredCopies(destinations int) int {
+	if o.Mode != BackupModeMirror || destinations <= 1 {
+		return 1
+	}
+	if o.Quorum > 0 && o.Quorum < destinations {
+		return o.Quorum
+	}
+	return destinations
+}
+
+// DefaultBackupOptions returns the backup options used when [backup] sets
+// nothing beyond the path.
+func DefaultBackupOptions() BackupOptions {
+	return BackupOptions{
+		Hash:      HashSHA256,
+		Verify:    true,
+		Collision: CollisionVersion,
+		Move:      true,
+	}
+}
+
+// ThrottleOptions are the [advanced] limits on backup I/O. A zero limit is
+// unlimited, so the zero value throttles nothing.
+//
+// Example:
+//
+//	[advanced]
+//	max-bandwidth=20MB/s
+//	max-ops-per-second=200
+//	full-speed=22:00-06:00
+type ThrottleOptions struct {
+	// MaxBandwidth caps the bytes per second read by backup copies.
+	MaxBandwidth uint64
+
+	// MaxOpsPerSecond caps file operations per second: every chunk a copy
+	// reads, every move and every delete counts as one.
+	MaxOpsPerSecond int
+
+	// FullSpeed lists daily windows in which neither limit applies.
+	FullSpeed []TimeWindow
+}
+
+// Limited reports whether any limit is set.
+func (o ThrottleOptions) Limited() bool {
+	return o.MaxBandwidth > 0 || o.MaxOpsPerSecond > 0
+}
+
+// TimeWindow is a daily period of local time, as offsets from midnight. A
+// window whose End is not after its Start runs past midnight, so 22:00-06:00
+// covers the night.
+type TimeWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains reports whether t's local time of day falls in the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	h, m, s := t.Clock()
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if w.Start < w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
 }
 
 // FilePlanConfig is the maintenance plan loaded from config.ini.
 //
 // This answers:
 // - Where are backups written?
+// - How are backup copies written and verified?
 // - Which files/folders are eligible for cleanup?
 // - Which paths require backup before deletion?
 type FilePlanConfig struct {
-	BackupDir string
-	Paths     []PathConfig
+	// BackupDir is the first (primary) entry of BackupDirs.
+	BackupDir  string
+	BackupDirs []string
+	Backup     BackupOptions
+	Throttle   ThrottleOptions
+	Paths      []PathConfig
 }
 
 // RuntimeConfig contains execution behavior that can come from defaults,
@@ -179,6 +504,14 @@ type AppConfig struct {
 	//   depending on how you choose to structure app wiring.
 	BackupDir string
 
+	// BackupDirs lists every backup destination in config order when [backup]
+	// path names several. The worker uses them according to Backup.Mode.
+	BackupDirs []string
+
+	// Backup holds the [backup] copy options (checksum, verification).
+	// app.Run() copies it from the file plan before starting the worker.
+	Backup BackupOptions
+
 	// LogSettings controls logging behavior (file vs stdout, log directory).
 	LogSettings logging.LogSettings
 
@@ -225,10 +558,70 @@ type AppConfig struct {
 	// - busy workstations
 	Cooldown time.Duration
 
+	// Throttle limits the bandwidth and file operations of backup copies and
+	// deletes, also within a single large file (unlike Cooldown).
+	// app.Run() copies it from the file plan before starting the worker.
+	Throttle ThrottleOptions
+
 	// Retries controls how many times a file copy is retried on failure.
 	//
 	// This is especially important for:
 	// - transient network issues
 	// - temporary file locks (e.g., antivirus scanners)
 	Retries int
+
+	// PlanFile enables plan (dry-run) mode when non-empty.
+	//
+	// The worker still walks, filters by age, and performs batch space
+	// accounting, but it never copies or deletes. Instead, every candidate file
+	// is written to a reviewable manifest (<PlanFile>.json and <PlanFile>.csv).
+	PlanFile string
+
+	// ApplyPlanFile executes a previously generated plan when non-empty.
+	//
+	// Instead of walking [paths], the files listed in the plan are fed straight
+	// into the batch processor. Files whose size or modification time changed
+	// since the plan was made are skipped.
+	ApplyPlanFile string
+
+	// Restore enables restore mode when Restore.Dates is non-empty. Files are
+	// copied back out of dated backup folders; nothing is deleted.
+	Restore RestoreOptions
+}
+
+// RestoreConflict decides what restore does when the destination file exists.
+type RestoreConflict string
+
+const (
+	// RestoreSkip leaves the existing file alone (default).
+	RestoreSkip RestoreConflict = "skip"
+	// RestoreOverwrite replaces the existing file.
+	RestoreOverwrite RestoreConflict = "overwrite"
+	// RestoreRename writes the restored file as "name (2).ext", ...
+	RestoreRename RestoreConflict = "rename"
+)
+
+// RestoreOptions are the -restore* CLI settings.
+type RestoreOptions struct {
+	// Dates selects dated backup folders: one folder name such as "30Jan26",
+	// or an inclusive range "01Jan26..31Jan26".
+	Dates string
+
+	// Match is an optional glob matched against each file's original source
+	// path ("**" spans directories), e.g. "C:/Data/Images/**/*.jpg" or "*.pdf".
+	Match string
+
+	// Target restores under this directory, keeping the backup's
+	// <folder-name>/<relative-path> layout, instead of to original locations.
+	Target string
+
+	// Conflict applies when the destination already exists. Empty means RestoreSkip.
+	Conflict RestoreConflict
+
+	// DryRun logs what would be restored without writing anything.
+	DryRun bool
+
+	// Key decrypts encrypted backups ([backup] encrypt-key). Encrypted
+	// backups fail to restore without it.
+	Key *BackupKey
 }